@@ -1,22 +1,37 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"monitorMultiview/internal/config"
 	"monitorMultiview/internal/monitor"
+	"monitorMultiview/internal/server"
+	"monitorMultiview/internal/status"
 	"monitorMultiview/internal/ui"
+	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
 type Model struct {
-	currentView   string // "main" or "detail"
-	mainView      *ui.MainViewModel
-	detailView    *ui.DetailViewModel
-	ffmpegMonitor *monitor.FFmpegMonitor
-	hlsMonitor    *monitor.HLSMonitor
+	currentView string // "main" or "detail"
+	mainView    *ui.MainViewModel
+	detailView  *ui.DetailViewModel
+	cfg         *config.Config
+	collector   *monitor.Collector
+	paused      bool
+	// banner is shown above the view while the config file is invalid
+	banner      string
+	width       int
+	height      int
 }
 
 func (m Model) Init() tea.Cmd {
@@ -32,11 +47,42 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg.String() {
 		case "q", "ctrl+c":
 			return m, tea.Quit
+		case "p":
+			// Freeze the display while investigating; collection keeps
+			// running so no restarts or sequence changes are missed
+			m.paused = !m.paused
+			m.mainView.SetPaused(m.paused)
+			if m.detailView != nil {
+				m.detailView.SetPaused(m.paused)
+			}
+			return m, nil
 		}
 
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m.resize()
+
+	case ui.ConfigReloadedMsg:
+		m.banner = ""
+		if msg.Err != nil {
+			m.banner = fmt.Sprintf("Config reload failed, keeping previous config: %v", msg.Err)
+		} else {
+			m.cfg = msg.Config
+			m.mainView.SetConfig(m.cfg)
+			if m.detailView != nil {
+				m.detailView.SetConfig(m.cfg)
+			}
+		}
+		return m.resize()
+
 	case ui.SwitchToDetailMsg:
 		m.currentView = "detail"
-		m.detailView = ui.NewDetailViewModel(msg.ChannelID, m.ffmpegMonitor, m.hlsMonitor)
+		m.detailView = ui.NewDetailViewModel(msg.ChannelID, m.cfg, m.collector)
+		m.detailView.SetPaused(m.paused)
+		if m.width > 0 {
+			m.detailView.Update(m.viewSize())
+		}
 		return m, m.detailView.Init()
 
 	case ui.SwitchToMainMsg:
@@ -55,37 +101,159 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) View() string {
+	var view string
+	if m.currentView == "main" {
+		view = m.mainView.View()
+	} else {
+		view = m.detailView.View()
+	}
+	if m.banner == "" {
+		return view
+	}
+	return m.renderBanner() + "\n" + view
+}
+
+func (m Model) renderBanner() string {
+	return ui.BannerStyle.Width(m.width).MaxWidth(m.width).Render(m.banner)
+}
+
+// viewSize is the window size left to the views below the banner.
+func (m Model) viewSize() tea.WindowSizeMsg {
+	size := tea.WindowSizeMsg{Width: m.width, Height: m.height}
+	if m.banner != "" {
+		size.Height -= lipgloss.Height(m.renderBanner())
+	}
+	return size
+}
+
+func (m Model) resize() (tea.Model, tea.Cmd) {
+	if m.width == 0 {
+		return m, nil
+	}
+	var cmd tea.Cmd
 	if m.currentView == "main" {
-		return m.mainView.View()
+		m.mainView, cmd = m.mainView.Update(m.viewSize())
+	} else {
+		m.detailView, cmd = m.detailView.Update(m.viewSize())
 	}
-	return m.detailView.View()
+	return m, cmd
 }
 
 func main() {
-	// Initialize configuration with command line arguments
-	config.InitConfig()
+	opts, err := config.ParseFlags(os.Args[1:])
+	if err != nil {
+		fmt.Printf("Error: %v\n\n", err)
+		config.PrintHelp(os.Stdout)
+		if opts.Once {
+			os.Exit(status.ExitUnknown)
+		}
+		os.Exit(2)
+	}
+	if opts.Help {
+		config.PrintHelp(os.Stdout)
+		return
+	}
+	if opts.GenerateConfig {
+		if err := config.GenerateConfigFile("multiview-monitor.yaml"); err != nil {
+			fmt.Printf("Error generating config file: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Generated default configuration file: multiview-monitor.yaml")
+		fmt.Println("Edit the file and run the program with: ./multiview-monitor -f multiview-monitor.yaml")
+		return
+	}
+
+	if opts.Once && !status.ValidFormat(opts.Output) {
+		fmt.Printf("Error: unknown output format %q (must be %s)\n", opts.Output, strings.Join(status.Formats, ", "))
+		os.Exit(status.ExitUnknown)
+	}
+
+	cfg, err := config.Load(opts)
+	if opts.CheckConfig {
+		os.Exit(checkConfig(cfg, err))
+	}
+	if err != nil {
+		var invalid *config.ValidationError
+		if errors.As(err, &invalid) {
+			fmt.Println("Configuration validation error:")
+			for _, problem := range invalid.Problems {
+				fmt.Printf("  %s\n", problem)
+			}
+		} else {
+			fmt.Printf("%v\n", err)
+		}
+		if opts.Once {
+			os.Exit(status.ExitUnknown)
+		}
+		os.Exit(1)
+	}
+	if opts.PrintConfig {
+		cfg.PrintEffective(os.Stdout)
+		return
+	}
+	if opts.Once {
+		os.Exit(printStatus(cfg, opts.Output))
+	}
+	log.Print(cfg.Summary())
 
 	// Initialize monitors
-	ffmpegMonitor := monitor.NewFFmpegMonitor()
-	hlsMonitor := monitor.NewHLSMonitor()
+	ffmpegMonitor := monitor.NewFFmpegMonitor(cfg)
+	hlsMonitor := monitor.NewHLSMonitor(cfg)
+	defer hlsMonitor.Close()
+
+	// Sample the monitors in the background; the views only read the
+	// published snapshots
+	collector := monitor.NewCollector(cfg, ffmpegMonitor, hlsMonitor)
+	if cfg.Channels.Discovery != monitor.DiscoverOff {
+		collector.SetDiscovery(monitor.NewDiscovery(cfg, ffmpegMonitor.Scanner()))
+	}
+	servers, err := startServers(cfg, collector)
+	if err != nil {
+		fmt.Printf("Error starting HTTP server: %v\n", err)
+		os.Exit(1)
+	}
+	defer closeServers(servers)
+	if opts.Headless && len(servers) == 0 {
+		fmt.Println("Error: --headless needs an HTTP listener such as --metrics-addr, --api-addr or --web-addr")
+		os.Exit(2)
+	}
+
+	collector.Start()
+	defer collector.Stop()
+
+	if opts.Headless {
+		runHeadless(cfg, collector)
+		return
+	}
 
 	// Initialize main view
-	mainView := ui.NewMainViewModel()
+	mainView := ui.NewMainViewModel(cfg, collector)
 
 	// Create model
 	model := Model{
-		currentView:   "main",
-		mainView:      mainView,
-		ffmpegMonitor: ffmpegMonitor,
-		hlsMonitor:    hlsMonitor,
+		currentView: "main",
+		mainView:    mainView,
+		cfg:         cfg,
+		collector:   collector,
 	}
 
-	// Create program with full screen mode
-	p := tea.NewProgram(
-		model,
-		tea.WithAltScreen(),
-		tea.WithMouseCellMotion(),
-	)
+	// Create program, in full screen mode unless disabled
+	programOptions := []tea.ProgramOption{tea.WithMouseCellMotion()}
+	if cfg.UI.Fullscreen {
+		programOptions = append(programOptions, tea.WithAltScreen())
+	}
+	p := tea.NewProgram(model, programOptions...)
+
+	// Pick up config file changes and SIGHUP while running
+	stopWatching := watchConfig(cfg, func(reloaded *config.Config, err error) {
+		if err != nil {
+			log.Printf("config reload failed: %v", err)
+		} else {
+			applyConfig(reloaded, cfg, collector)
+		}
+		p.Send(ui.ConfigReloadedMsg{Config: reloaded, Err: err})
+	})
+	defer stopWatching()
 
 	// Run program
 	if _, err := p.Run(); err != nil {
@@ -94,6 +262,151 @@ func main() {
 	}
 }
 
+// printStatus collects one reading of every channel, prints it in format and
+// returns the Nagios exit code of the worst channel.
+func printStatus(cfg *config.Config, format string) int {
+	hlsMonitor := monitor.NewHLSMonitor(cfg)
+	defer hlsMonitor.Close()
+
+	ffmpegMonitor := monitor.NewFFmpegMonitor(cfg)
+	collector := monitor.NewCollector(cfg, ffmpegMonitor, hlsMonitor)
+	if cfg.Channels.Discovery != monitor.DiscoverOff {
+		collector.SetDiscovery(monitor.NewDiscovery(cfg, ffmpegMonitor.Scanner()))
+	}
+
+	report := status.Build(collector.CollectOnce())
+	if err := status.Write(os.Stdout, report, format); err != nil {
+		fmt.Printf("Error writing status: %v\n", err)
+		return status.ExitUnknown
+	}
+	return report.ExitCode()
+}
+
+// startServers starts an HTTP listener for every configured address.
+// Endpoints configured with the same address share its listener; the web
+// dashboard brings the API it reads along.
+func startServers(cfg *config.Config, collector *monitor.Collector) ([]*server.Server, error) {
+	var addrs []string
+	muxes := make(map[string]*http.ServeMux)
+	paths := make(map[string][]string)
+	handle := func(addr, path string, handler http.Handler) {
+		if addr == "" {
+			return
+		}
+		if muxes[addr] == nil {
+			muxes[addr] = http.NewServeMux()
+			addrs = append(addrs, addr)
+		}
+		if slices.Contains(paths[addr], path) {
+			return
+		}
+		muxes[addr].Handle(path, handler)
+		paths[addr] = append(paths[addr], path)
+	}
+	handle(cfg.HTTP.MetricsAddr, "/metrics", server.Metrics(collector))
+	handle(cfg.HTTP.APIAddr, "/api/", server.API(collector))
+	handle(cfg.HTTP.WebAddr, "/", server.Web())
+	handle(cfg.HTTP.WebAddr, "/api/", server.API(collector))
+
+	var servers []*server.Server
+	for _, addr := range addrs {
+		srv, err := server.Start(addr, muxes[addr])
+		if err != nil {
+			closeServers(servers)
+			return nil, err
+		}
+		log.Printf("serving %s on http://%s", strings.Join(paths[addr], ", "), srv.Addr())
+		servers = append(servers, srv)
+	}
+	return servers, nil
+}
+
+func closeServers(servers []*server.Server) {
+	for _, srv := range servers {
+		srv.Close()
+	}
+}
+
+// runHeadless keeps collecting for the HTTP listeners until SIGINT or
+// SIGTERM, reloading the configuration like the TUI does.
+func runHeadless(cfg *config.Config, collector *monitor.Collector) {
+	stopWatching := watchConfig(cfg, func(reloaded *config.Config, err error) {
+		if err != nil {
+			log.Printf("config reload failed, keeping previous config: %v", err)
+			return
+		}
+		applyConfig(reloaded, cfg, collector)
+	})
+	defer stopWatching()
+
+	log.Print("running headless")
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	sig := <-signals
+	signal.Stop(signals)
+	log.Printf("received %s, shutting down", sig)
+}
+
+// watchConfig reloads the configuration on file changes and SIGHUP. Without
+// a config file there is nothing to reload, but SIGHUP is still caught so
+// that a reload request does not terminate the monitor.
+func watchConfig(cfg *config.Config, onReload func(*config.Config, error)) func() {
+	if len(cfg.Files()) > 0 {
+		return config.WatchConfig(cfg, time.Second, onReload)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			log.Print("received SIGHUP, but no config file was loaded; nothing to reload")
+		}
+	}()
+	return func() {
+		signal.Stop(hup)
+		close(hup)
+	}
+}
+
+// checkConfig reports the result of loading the configuration for
+// --check-config and returns the exit code.
+func checkConfig(cfg *config.Config, err error) int {
+	if err == nil {
+		for _, warning := range cfg.Warnings() {
+			fmt.Printf("warning: %s\n", warning)
+		}
+		files := "defaults only"
+		if len(cfg.Files()) > 0 {
+			files = strings.Join(cfg.Files(), ", ")
+		}
+		fmt.Printf("Configuration OK (%s)\n", files)
+		return 0
+	}
+
+	var invalid *config.ValidationError
+	if !errors.As(err, &invalid) {
+		fmt.Println(err)
+		return 1
+	}
+	for _, problem := range invalid.Problems {
+		fmt.Println(problem)
+	}
+	fmt.Printf("%d problem(s) found\n", len(invalid.Problems))
+	return 1
+}
+
+// applyConfig hands a reloaded configuration to the running collector. The
+// watch mode and discovery are set up once and only change on restart.
+func applyConfig(cfg, started *config.Config, collector *monitor.Collector) {
+	collector.ApplyConfig(cfg)
+
+	if cfg.HLS.WatchMode != started.HLS.WatchMode || cfg.Channels.Discovery != started.Channels.Discovery || cfg.HTTP != started.HTTP {
+		log.Printf("config reloaded; hls.watch_mode, channels.discovery and http changes take effect after a restart")
+	} else {
+		log.Printf("config reloaded from %s", strings.Join(cfg.Files(), ", "))
+	}
+}
+
 func init() {
 	// Configure logging to file instead of stdout to avoid interfering with TUI
 	logFile, err := os.OpenFile("monitor.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
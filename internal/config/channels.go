@@ -1,10 +1,10 @@
 package config
 
 import (
-	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,24 +14,78 @@ type Config struct {
 	FFmpeg FFmpegConfig `yaml:"ffmpeg"`
 	Channels ChannelsConfig `yaml:"channels"`
 	UI UIConfig `yaml:"ui"`
+	Intervals IntervalsConfig `yaml:"intervals"`
+	HTTP HTTPConfig `yaml:"http"`
 	Logging LoggingConfig `yaml:"logging"`
 	App AppConfig `yaml:"app"`
+
+	// files are the loaded config files and options what Load was called
+	// with, kept for Reload; sources maps every setting to its layer
+	files []string
+	options Options
+	sources map[string]Source
+	// positions holds where every key, including list entries such as
+	// channels.list[0], was last set in a file; problems lists what went
+	// wrong decoding the files
+	positions map[string]Source
+	problems []string
+	// countCleared is set when Load dropped the default channel count
+	// because channels are listed or discovered
+	countCleared bool
 }
 
 type HLSConfig struct {
 	BasePath string `yaml:"base_path"`
 	ChannelDirPattern string `yaml:"channel_dir_pattern"`
+	// Health thresholds as multiples of #EXT-X-TARGETDURATION
+	WarnFactor float64 `yaml:"warn_factor"`
+	StaleFactor float64 `yaml:"stale_factor"`
+	// WatchMode selects how directories are tracked: auto, inotify or poll
+	WatchMode string `yaml:"watch_mode"`
+	// RescanInterval is the full rescan period in seconds while watching
+	RescanInterval int `yaml:"rescan_interval"`
 }
 
 type FFmpegConfig struct {
 	StartPort int `yaml:"start_port"`
 	PortIncrement int `yaml:"port_increment"`
+	// MatchStrategy selects how processes are assigned to channels:
+	// port, output_path, regex or label
+	MatchStrategy string `yaml:"match_strategy"`
+	MatchRegex string `yaml:"match_regex"`
+	MatchLabel string `yaml:"match_label"`
+	// A channel is flapping after FlapThreshold restarts within FlapWindow seconds
+	FlapThreshold int `yaml:"flap_threshold"`
+	FlapWindow int `yaml:"flap_window"`
 }
 
 type ChannelsConfig struct {
 	Count int `yaml:"count"`
 	IDFormat string `yaml:"id_format"`
 	NameFormat string `yaml:"name_format"`
+	// Discovery adds channels found at runtime: off, hls, ffmpeg or both
+	Discovery string `yaml:"discovery"`
+	// List defines channels explicitly. An entry with the number or ID of a
+	// generated channel overrides its fields, any other entry is added.
+	List []ChannelDefinition `yaml:"list,omitempty"`
+}
+
+// ChannelDefinition is one entry of channels.list. Empty fields keep the
+// value of the generated channel it overrides.
+type ChannelDefinition struct {
+	ID string `yaml:"id,omitempty"`
+	Number int `yaml:"number,omitempty"`
+	Name string `yaml:"name,omitempty"`
+	Port int `yaml:"port,omitempty"`
+	Ports []int `yaml:"ports,omitempty"`
+	// Path is the HLS directory, relative to hls.base_path unless absolute
+	Path string `yaml:"path,omitempty"`
+	MasterPlaylist string `yaml:"master_playlist,omitempty"`
+	// Renditions lists the media playlists, relative to Path, that must exist
+	Renditions []string `yaml:"renditions,omitempty"`
+	Tags []string `yaml:"tags,omitempty"`
+	WarnFactor float64 `yaml:"warn_factor,omitempty"`
+	StaleFactor float64 `yaml:"stale_factor,omitempty"`
 }
 
 type UIConfig struct {
@@ -40,6 +94,31 @@ type UIConfig struct {
 	Theme string `yaml:"theme"`
 }
 
+// IntervalsConfig sets how often each subsystem refreshes, e.g. "2s" or
+// "500ms". Unset intervals fall back to ui.refresh_interval.
+type IntervalsConfig struct {
+	// Process scanning of /proc
+	Process time.Duration `yaml:"process"`
+	// HLS directory scanning; with inotify only missing directories and
+	// the periodic rescan are checked this often
+	HLSScan time.Duration `yaml:"hls_scan"`
+	// Playlist parsing and health evaluation
+	Playlist time.Duration `yaml:"playlist"`
+	// UI redraw
+	UI time.Duration `yaml:"ui"`
+}
+
+// HTTPConfig sets the HTTP listeners, e.g. ":9273". Empty addresses are
+// disabled; equal addresses share one listener.
+type HTTPConfig struct {
+	// MetricsAddr serves Prometheus metrics at /metrics
+	MetricsAddr string `yaml:"metrics_addr"`
+	// APIAddr serves the JSON API at /api/v1/
+	APIAddr string `yaml:"api_addr"`
+	// WebAddr serves the web dashboard at / together with the API it uses
+	WebAddr string `yaml:"web_addr"`
+}
+
 type LoggingConfig struct {
 	File string `yaml:"file"`
 	Level string `yaml:"level"`
@@ -52,185 +131,82 @@ type AppConfig struct {
 }
 
 type Channel struct {
-	ID     string
-	Name   string
-	Port   int
-	Path   string
+	ID     string `json:"id"`
+	Number int    `json:"number"`
+	Name   string `json:"name"`
+	// Port is the first of Ports, 0 if the channel has none
+	Port  int    `json:"port"`
+	Ports []int  `json:"ports"`
+	Path  string `json:"path"`
+
+	MasterPlaylist string   `json:"master_playlist"`
+	Renditions     []string `json:"renditions"`
+	Tags           []string `json:"tags"`
+	// Health thresholds, defaulting to the hls section
+	WarnFactor  float64 `json:"warn_factor"`
+	StaleFactor float64 `json:"stale_factor"`
+
+	// Discovered names the discovery source of a channel that is not
+	// configured; Vanished is set once it is no longer found
+	Discovered string    `json:"discovered"`
+	Vanished   bool      `json:"vanished"`
+	VanishedAt time.Time `json:"vanished_at"`
 }
 
-var GlobalConfig = Config{
-	HLS: HLSConfig{
-		BasePath: "/output",
-		ChannelDirPattern: "channel%02d",
-	},
-	FFmpeg: FFmpegConfig{
-		StartPort: 8001,
-		PortIncrement: 1,
-	},
-	Channels: ChannelsConfig{
-		Count: 24,
-		IDFormat: "ch%02d",
-		NameFormat: "Channel %02d",
-	},
-	UI: UIConfig{
-		RefreshInterval: 1,
-		Fullscreen: true,
-		Theme: "dark",
-	},
-	Logging: LoggingConfig{
-		File: "monitor.log",
-		Level: "info",
-	},
-	App: AppConfig{
-		Name: "MultiView Monitor",
-		Version: "1.0.0",
-		Description: "Real-time FFmpeg and HLS monitoring tool",
-	},
-}
-
-func InitConfig() {
-	var configFile string
-	var hlsPath string
-	var channelCount int
-	var startPort int
-	var generateConfig bool
-
-	flag.StringVar(&configFile, "config", "", "Path to configuration file")
-	flag.StringVar(&configFile, "f", "", "Path to configuration file (short)")
-	flag.BoolVar(&generateConfig, "generate-config", false, "Generate default configuration file")
-	flag.StringVar(&hlsPath, "hls-path", "", "Base path for HLS package directories")
-	flag.StringVar(&hlsPath, "p", "", "Base path for HLS package directories (short)")
-	flag.IntVar(&channelCount, "channels", 0, "Number of channels to monitor")
-	flag.IntVar(&channelCount, "c", 0, "Number of channels to monitor (short)")
-	flag.IntVar(&startPort, "start-port", 0, "Starting port number for FFmpeg processes")
-	flag.IntVar(&startPort, "s", 0, "Starting port number for FFmpeg processes (short)")
-
-	help := flag.Bool("help", false, "Show help message")
-	flag.BoolVar(help, "h", false, "Show help message (short)")
-
-	flag.Parse()
-
-	if *help {
-		printHelp()
-		os.Exit(0)
-	}
-
-	if generateConfig {
-		if err := GenerateConfigFile("multiview-monitor.yaml"); err != nil {
-			fmt.Printf("Error generating config file: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Println("Generated default configuration file: multiview-monitor.yaml")
-		fmt.Println("Edit the file and run the program with: ./multiview-monitor -f multiview-monitor.yaml")
-		os.Exit(0)
-	}
-
-	// Load configuration file if specified
-	if configFile != "" {
-		if err := LoadConfigFile(configFile); err != nil {
-			fmt.Printf("Error loading config file %s: %v\n", configFile, err)
-			os.Exit(1)
-		}
-	} else {
-		// Try to load from default locations
-		defaultPaths := []string{
-			"multiview-monitor.yaml",
-			"configs/multiview-monitor.yaml",
-			filepath.Join(os.Getenv("HOME"), ".multiview-monitor.yaml"),
+// HasPort reports whether port is one of the channel's input ports.
+func (c Channel) HasPort(port int) bool {
+	for _, p := range c.Ports {
+		if p == port {
+			return true
 		}
-		
-		for _, path := range defaultPaths {
-			if _, err := os.Stat(path); err == nil {
-				fmt.Printf("Loading configuration from: %s\n", path)
-				if err := LoadConfigFile(path); err != nil {
-					fmt.Printf("Warning: Error loading config file %s: %v\n", path, err)
-				} else {
-					break
-				}
-			}
-		}
-	}
-
-	// Command line arguments override config file settings
-	if hlsPath != "" {
-		GlobalConfig.HLS.BasePath = hlsPath
-	}
-	if channelCount > 0 {
-		GlobalConfig.Channels.Count = channelCount
-	}
-	if startPort > 0 {
-		GlobalConfig.FFmpeg.StartPort = startPort
 	}
-
-	// Validate configuration
-	if err := ValidateConfig(); err != nil {
-		fmt.Printf("Configuration validation error: %v\n", err)
-		os.Exit(1)
-	}
-
-	printCurrentConfig()
+	return false
 }
 
-func LoadConfigFile(filename string) error {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
-	}
-
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("failed to parse YAML config: %w", err)
-	}
-
-	// Merge with global config (only non-zero values)
-	if config.HLS.BasePath != "" {
-		GlobalConfig.HLS.BasePath = config.HLS.BasePath
-	}
-	if config.HLS.ChannelDirPattern != "" {
-		GlobalConfig.HLS.ChannelDirPattern = config.HLS.ChannelDirPattern
-	}
-	if config.FFmpeg.StartPort > 0 {
-		GlobalConfig.FFmpeg.StartPort = config.FFmpeg.StartPort
-	}
-	if config.FFmpeg.PortIncrement > 0 {
-		GlobalConfig.FFmpeg.PortIncrement = config.FFmpeg.PortIncrement
-	}
-	if config.Channels.Count > 0 {
-		GlobalConfig.Channels.Count = config.Channels.Count
-	}
-	if config.Channels.IDFormat != "" {
-		GlobalConfig.Channels.IDFormat = config.Channels.IDFormat
-	}
-	if config.Channels.NameFormat != "" {
-		GlobalConfig.Channels.NameFormat = config.Channels.NameFormat
+// DefaultConfig returns the built-in settings used below the config file.
+func DefaultConfig() *Config {
+	return &Config{
+		HLS: HLSConfig{
+			BasePath: "/output",
+			ChannelDirPattern: "channel%02d",
+			WarnFactor: 1.5,
+			StaleFactor: 3,
+			WatchMode: "auto",
+			RescanInterval: 60,
+		},
+		FFmpeg: FFmpegConfig{
+			StartPort: 8001,
+			PortIncrement: 1,
+			MatchStrategy: "port",
+			MatchLabel: "MULTIVIEW_CHANNEL",
+			FlapThreshold: 3,
+			FlapWindow: 600,
+		},
+		Channels: ChannelsConfig{
+			Count: 24,
+			IDFormat: "ch%02d",
+			NameFormat: "Channel %02d",
+			Discovery: "off",
+		},
+		UI: UIConfig{
+			RefreshInterval: 1,
+			Fullscreen: true,
+			Theme: "dark",
+		},
+		Logging: LoggingConfig{
+			File: "monitor.log",
+			Level: "info",
+		},
+		App: AppConfig{
+			Name: "MultiView Monitor",
+			Version: "1.0.0",
+			Description: "Real-time FFmpeg and HLS monitoring tool",
+		},
 	}
-	if config.UI.RefreshInterval > 0 {
-		GlobalConfig.UI.RefreshInterval = config.UI.RefreshInterval
-	}
-	if config.UI.Theme != "" {
-		GlobalConfig.UI.Theme = config.UI.Theme
-	}
-	if config.Logging.File != "" {
-		GlobalConfig.Logging.File = config.Logging.File
-	}
-	if config.Logging.Level != "" {
-		GlobalConfig.Logging.Level = config.Logging.Level
-	}
-	if config.App.Name != "" {
-		GlobalConfig.App.Name = config.App.Name
-	}
-	if config.App.Version != "" {
-		GlobalConfig.App.Version = config.App.Version
-	}
-	if config.App.Description != "" {
-		GlobalConfig.App.Description = config.App.Description
-	}
-
-	return nil
 }
 
 func GenerateConfigFile(filename string) error {
-	data, err := yaml.Marshal(&GlobalConfig)
+	data, err := yaml.Marshal(DefaultConfig())
 	if err != nil {
 		return fmt.Errorf("failed to marshal config to YAML: %w", err)
 	}
@@ -238,7 +214,9 @@ func GenerateConfigFile(filename string) error {
 	header := `# MultiView Monitor Configuration File
 # This file contains settings for the MultiView Monitor application
 # 
-# Priority: Command line arguments > Config file > Default values
+# Priority: Command line arguments > MULTIVIEW_* environment variables >
+#           config file > ~/.multiview-monitor.yaml > /etc/multiview-monitor.yaml >
+#           default values
 #
 # Examples:
 #   ./multiview-monitor -f multiview-monitor.yaml
@@ -257,80 +235,122 @@ func GenerateConfigFile(filename string) error {
 	return nil
 }
 
-func ValidateConfig() error {
-	if GlobalConfig.Channels.Count <= 0 || GlobalConfig.Channels.Count > 999 {
-		return fmt.Errorf("invalid channel count: %d (must be 1-999)", GlobalConfig.Channels.Count)
-	}
-	if GlobalConfig.FFmpeg.StartPort <= 0 || GlobalConfig.FFmpeg.StartPort > 65535 {
-		return fmt.Errorf("invalid start port: %d (must be 1-65535)", GlobalConfig.FFmpeg.StartPort)
+// MinInterval is the shortest accepted refresh interval.
+const MinInterval = 100 * time.Millisecond
+
+// RefreshIntervals returns the refresh interval of every subsystem, with
+// unset ones taken from ui.refresh_interval.
+func (c *Config) RefreshIntervals() IntervalsConfig {
+	fallback := time.Duration(c.UI.RefreshInterval) * time.Second
+	intervals := c.Intervals
+	for _, interval := range []*time.Duration{&intervals.Process, &intervals.HLSScan, &intervals.Playlist, &intervals.UI} {
+		if *interval <= 0 {
+			*interval = fallback
+		}
 	}
-	if GlobalConfig.HLS.BasePath == "" {
-		return fmt.Errorf("HLS base path cannot be empty")
+	return intervals
+}
+
+// ChannelList returns the generated channel range with the entries of
+// channels.list applied on top, in number order followed by added entries.
+func (c *Config) ChannelList() []Channel {
+	channels := make([]Channel, c.Channels.Count)
+	for i := 0; i < c.Channels.Count; i++ {
+		channelNum := i + 1
+		port := c.FFmpeg.StartPort + (i * c.FFmpeg.PortIncrement)
+		channels[i] = Channel{
+			ID:          fmt.Sprintf(c.Channels.IDFormat, channelNum),
+			Number:      channelNum,
+			Name:        fmt.Sprintf(c.Channels.NameFormat, channelNum),
+			Port:        port,
+			Ports:       []int{port},
+			Path:        filepath.Join(c.HLS.BasePath, fmt.Sprintf(c.HLS.ChannelDirPattern, channelNum)),
+			WarnFactor:  c.HLS.WarnFactor,
+			StaleFactor: c.HLS.StaleFactor,
+		}
 	}
-	if GlobalConfig.UI.RefreshInterval <= 0 {
-		return fmt.Errorf("refresh interval must be positive: %d", GlobalConfig.UI.RefreshInterval)
+
+	for _, def := range c.Channels.List {
+		if i := generatedIndex(channels, c.Channels.Count, def); i >= 0 {
+			channels[i] = applyDefinition(channels[i], c.HLS.BasePath, def)
+			continue
+		}
+		channels = append(channels, applyDefinition(Channel{
+			Number:      def.Number,
+			Name:        def.ID,
+			Path:        filepath.Join(c.HLS.BasePath, def.ID),
+			WarnFactor:  c.HLS.WarnFactor,
+			StaleFactor: c.HLS.StaleFactor,
+		}, c.HLS.BasePath, def))
 	}
-	
-	return nil
+	return channels
 }
 
-func printHelp() {
-	fmt.Printf("%s - %s\n", GlobalConfig.App.Name, GlobalConfig.App.Description)
-	fmt.Println("")
-	fmt.Println("Usage:")
-	fmt.Printf("  %s [options]\n", os.Args[0])
-	fmt.Println("")
-	fmt.Println("Configuration:")
-	fmt.Println("  -f, --config string        Path to configuration file")
-	fmt.Println("  --generate-config          Generate default configuration file")
-	fmt.Println("")
-	fmt.Println("Options (override config file):")
-	flag.PrintDefaults()
-	fmt.Println("")
-	fmt.Println("Examples:")
-	fmt.Printf("  %s --generate-config\n", os.Args[0])
-	fmt.Printf("  %s -f myconfig.yaml\n", os.Args[0])
-	fmt.Printf("  %s -f myconfig.yaml -c 12 -p /custom/path\n", os.Args[0])
-	fmt.Printf("  %s -p /data/hls -c 12 -s 9001\n", os.Args[0])
-	fmt.Println("")
-	fmt.Println("Config file locations (checked in order):")
-	fmt.Println("  1. ./multiview-monitor.yaml")
-	fmt.Println("  2. ./configs/multiview-monitor.yaml")
-	fmt.Println("  3. ~/.multiview-monitor.yaml")
-	fmt.Println("")
-	fmt.Println("Keyboard shortcuts:")
-	fmt.Println("  Tab       - Switch between FFmpeg and HLS panels")
-	fmt.Println("  ↑/↓       - Navigate channels")
-	fmt.Println("  Enter     - View channel details")
-	fmt.Println("  Esc       - Return to main view")
-	fmt.Println("  q         - Quit")
+// generatedIndex finds the generated channel a list entry overrides, by
+// number first and then by ID.
+func generatedIndex(channels []Channel, count int, def ChannelDefinition) int {
+	if def.Number > 0 && def.Number <= count {
+		return def.Number - 1
+	}
+	for i := 0; i < count; i++ {
+		if def.ID != "" && channels[i].ID == def.ID {
+			return i
+		}
+	}
+	return -1
 }
 
-func printCurrentConfig() {
-	fmt.Printf("Starting %s v%s with:\n", GlobalConfig.App.Name, GlobalConfig.App.Version)
-	fmt.Printf("  HLS Base Path: %s\n", GlobalConfig.HLS.BasePath)
-	fmt.Printf("  Channels: %d\n", GlobalConfig.Channels.Count)
-	fmt.Printf("  Start Port: %d\n", GlobalConfig.FFmpeg.StartPort)
-	fmt.Printf("  Refresh Interval: %ds\n", GlobalConfig.UI.RefreshInterval)
-	fmt.Println("")
+func applyDefinition(ch Channel, basePath string, def ChannelDefinition) Channel {
+	if def.ID != "" {
+		ch.ID = def.ID
+	}
+	if def.Name != "" {
+		ch.Name = def.Name
+	}
+	if ports := def.ports(); len(ports) > 0 {
+		ch.Ports = ports
+		ch.Port = ports[0]
+	}
+	if def.Path != "" {
+		ch.Path = def.Path
+		if !filepath.IsAbs(def.Path) {
+			ch.Path = filepath.Join(basePath, def.Path)
+		}
+	}
+	if def.MasterPlaylist != "" {
+		ch.MasterPlaylist = def.MasterPlaylist
+	}
+	if len(def.Renditions) > 0 {
+		ch.Renditions = def.Renditions
+	}
+	if len(def.Tags) > 0 {
+		ch.Tags = def.Tags
+	}
+	if def.WarnFactor > 0 {
+		ch.WarnFactor = def.WarnFactor
+	}
+	if def.StaleFactor > 0 {
+		ch.StaleFactor = def.StaleFactor
+	}
+	return ch
 }
 
-func GetChannels() []Channel {
-	channels := make([]Channel, GlobalConfig.Channels.Count)
-	for i := 0; i < GlobalConfig.Channels.Count; i++ {
-		channelNum := i + 1
-		channels[i] = Channel{
-			ID:   fmt.Sprintf(GlobalConfig.Channels.IDFormat, channelNum),
-			Name: fmt.Sprintf(GlobalConfig.Channels.NameFormat, channelNum),
-			Port: GlobalConfig.FFmpeg.StartPort + (i * GlobalConfig.FFmpeg.PortIncrement),
-			Path: filepath.Join(GlobalConfig.HLS.BasePath, fmt.Sprintf(GlobalConfig.HLS.ChannelDirPattern, channelNum)),
+// ports merges the port and ports fields of a list entry.
+func (def ChannelDefinition) ports() []int {
+	var ports []int
+	if def.Port > 0 {
+		ports = append(ports, def.Port)
+	}
+	for _, port := range def.Ports {
+		if port != def.Port {
+			ports = append(ports, port)
 		}
 	}
-	return channels
+	return ports
 }
 
-func GetChannelByID(id string) *Channel {
-	channels := GetChannels()
+func (c *Config) ChannelByID(id string) *Channel {
+	channels := c.ChannelList()
 	for _, ch := range channels {
 		if ch.ID == id {
 			return &ch
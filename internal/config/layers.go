@@ -0,0 +1,273 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Configuration layers, lowest precedence first. Every layer only changes
+// the settings it actually contains, so false, zero and empty values
+// override the layers below as well.
+const (
+	LayerDefault = "default"
+	LayerSystem  = "system"
+	LayerUser    = "user"
+	LayerFile    = "file"
+	LayerEnv     = "env"
+	LayerFlag    = "flag"
+)
+
+// EnvPrefix starts the environment variable of every setting, e.g.
+// MULTIVIEW_HLS_BASE_PATH for hls.base_path.
+const EnvPrefix = "MULTIVIEW_"
+
+// Source tells where the effective value of a setting came from.
+type Source struct {
+	Layer string
+	// Origin is the file, variable or flag within the layer
+	Origin string
+	// Line and Column locate the key in a file, starting at 1
+	Line   int
+	Column int
+}
+
+func (s Source) String() string {
+	switch {
+	case s.Origin == "":
+		return s.Layer
+	case s.Line > 0:
+		return fmt.Sprintf("%s %s:%d:%d", s.Layer, s.Origin, s.Line, s.Column)
+	default:
+		return s.Layer + " " + s.Origin
+	}
+}
+
+// setting is one leaf of the configuration, addressed by its dotted YAML
+// key such as "ui.fullscreen".
+type setting struct {
+	key   string
+	value reflect.Value
+}
+
+// settings lists the leaves of c in declaration order.
+func (c *Config) settings() []setting {
+	return collectSettings("", reflect.ValueOf(c).Elem(), nil)
+}
+
+func collectSettings(prefix string, v reflect.Value, out []setting) []setting {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		key := prefix + name
+		if field.Type.Kind() == reflect.Struct {
+			out = collectSettings(key+".", v.Field(i), out)
+			continue
+		}
+		out = append(out, setting{key: key, value: v.Field(i)})
+	}
+	return out
+}
+
+// EnvName returns the environment variable for a setting key.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// applyFile decodes a config file on top of c and records the settings it
+// contains under layer. Unknown keys and values of the wrong type are kept
+// as problems for Validate, so all of them are reported together.
+func (c *Config) applyFile(layer, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse YAML config: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		c.files = append(c.files, path)
+		return nil
+	}
+	root := doc.Content[0]
+
+	checkFields(path, "", root, reflect.TypeOf(*c), &c.problems)
+
+	// Decoding into the existing values keeps everything the file does not
+	// mention
+	if err := root.Decode(c); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return fmt.Errorf("failed to parse YAML config: %w", err)
+		}
+		for _, msg := range typeErr.Errors {
+			// "line 3: cannot unmarshal ..." becomes "file:3: cannot ..."
+			if rest, ok := strings.CutPrefix(msg, "line "); ok {
+				c.problems = append(c.problems, path+":"+rest)
+			} else {
+				c.problems = append(c.problems, path+": "+msg)
+			}
+		}
+	}
+
+	positions := make(map[string]Source)
+	collectKeys("", root, Source{Layer: layer, Origin: path}, positions)
+	for key, source := range positions {
+		c.positions[key] = source
+	}
+	for _, s := range c.settings() {
+		if source, ok := positions[s.key]; ok {
+			c.sources[s.key] = source
+		}
+	}
+	c.files = append(c.files, path)
+	return nil
+}
+
+// collectKeys records the position of every mapping key and sequence entry
+// below node by its dotted path.
+func collectKeys(prefix string, node *yaml.Node, source Source, keys map[string]Source) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			key := joinKey(prefix, keyNode.Value)
+			source.Line, source.Column = keyNode.Line, keyNode.Column
+			keys[key] = source
+			collectKeys(key, node.Content[i+1], source, keys)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			key := fmt.Sprintf("%s[%d]", prefix, i)
+			source.Line, source.Column = item.Line, item.Column
+			keys[key] = source
+			collectKeys(key, item, source, keys)
+		}
+	}
+}
+
+// applyEnv sets every setting that has a MULTIVIEW_* variable in env.
+// Values that do not parse are kept as problems for Validate, like those in
+// files.
+func (c *Config) applyEnv(env []string) {
+	vars := make(map[string]string)
+	for _, kv := range env {
+		if name, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(name, EnvPrefix) {
+			vars[name] = value
+		}
+	}
+
+	for _, s := range c.settings() {
+		name := EnvName(s.key)
+		value, ok := vars[name]
+		if !ok {
+			continue
+		}
+		if err := setValue(s.value, value); err != nil {
+			c.problems = append(c.problems, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		c.sources[s.key] = Source{Layer: LayerEnv, Origin: name}
+		c.forgetPositions(s.key)
+	}
+}
+
+// forgetPositions drops the file positions below key once a later layer
+// replaces it as a whole.
+func (c *Config) forgetPositions(key string) {
+	for k := range c.positions {
+		if strings.HasPrefix(k, key+"[") || strings.HasPrefix(k, key+".") {
+			delete(c.positions, k)
+		}
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(v reflect.Value, text string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(text)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", text)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", text)
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", text)
+		}
+		v.SetBool(b)
+	default:
+		// Lists are given in YAML flow syntax, e.g. [{id: news, port: 9001}]
+		decoded := reflect.New(v.Type())
+		if err := yaml.Unmarshal([]byte(text), decoded.Interface()); err != nil {
+			return fmt.Errorf("invalid YAML value: %w", err)
+		}
+		v.Set(decoded.Elem())
+	}
+	return nil
+}
+
+// Source returns where the effective value of a setting key came from.
+func (c *Config) Source(key string) Source {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return Source{Layer: LayerDefault}
+}
+
+// PrintEffective writes every setting with its effective value and the
+// layer it came from.
+func (c *Config) PrintEffective(w io.Writer) {
+	settings := c.settings()
+	width := 0
+	for _, s := range settings {
+		width = max(width, len(s.key))
+	}
+	for _, s := range settings {
+		fmt.Fprintf(w, "%-*s  %-30s  %s\n", width, s.key, formatValue(s.value), c.Source(s.key))
+	}
+}
+
+func formatValue(v reflect.Value) string {
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Kind() == reflect.String:
+		return strconv.Quote(v.String())
+	case v.Kind() == reflect.Slice && v.Len() == 1:
+		return "[1 entry]"
+	case v.Kind() == reflect.Slice:
+		return fmt.Sprintf("[%d entries]", v.Len())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,310 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Options selects the config files, environment and command line overrides
+// Load layers on top of the defaults.
+type Options struct {
+	// SystemFile and UserFile are loaded first, if they exist
+	SystemFile string
+	UserFile   string
+	// File is loaded if set; otherwise the first of SearchPaths that exists
+	File        string
+	SearchPaths []string
+	// Env holds KEY=value pairs, searched for MULTIVIEW_* variables
+	Env []string
+
+	HLSPath      string
+	ChannelCount int
+	StartPort    int
+	MetricsAddr  string
+	APIAddr      string
+	WebAddr      string
+	// explicit holds the settings given as flags, so zero values given on
+	// the command line override the other layers too
+	explicit map[string]bool
+
+	// Set by ParseFlags for main to act on
+	GenerateConfig bool
+	Help           bool
+	PrintConfig    bool
+	CheckConfig    bool
+	// Once collects a single reading, prints it in Output format and exits
+	Once   bool
+	Output string
+	// Headless runs the collector and HTTP listeners without the TUI
+	Headless bool
+}
+
+// DefaultOptions loads the system and user files, the first config file
+// found in the working directory and the process environment.
+func DefaultOptions() Options {
+	opts := Options{
+		SystemFile:  "/etc/multiview-monitor.yaml",
+		SearchPaths: DefaultSearchPaths(),
+		Env:         os.Environ(),
+	}
+	if home := os.Getenv("HOME"); home != "" {
+		opts.UserFile = filepath.Join(home, ".multiview-monitor.yaml")
+	}
+	return opts
+}
+
+// DefaultSearchPaths are the config file locations checked in order when no
+// file is given.
+func DefaultSearchPaths() []string {
+	return []string{
+		"multiview-monitor.yaml",
+		"configs/multiview-monitor.yaml",
+	}
+}
+
+// flagSettings maps the override flags to the settings they set.
+var flagSettings = map[string]string{
+	"hls-path":     "hls.base_path",
+	"p":            "hls.base_path",
+	"channels":     "channels.count",
+	"c":            "channels.count",
+	"start-port":   "ffmpeg.start_port",
+	"s":            "ffmpeg.start_port",
+	"metrics-addr": "http.metrics_addr",
+	"api-addr":     "http.api_addr",
+	"web-addr":     "http.web_addr",
+}
+
+// ParseFlags reads the command line options from args, without the program
+// name, on top of DefaultOptions. A leading "status" subcommand is the same
+// as --once.
+func ParseFlags(args []string) (Options, error) {
+	opts := DefaultOptions()
+	subcommand := len(args) > 0 && args[0] == "status"
+	if subcommand {
+		args = args[1:]
+	}
+	fs := newFlagSet(&opts)
+	fs.SetOutput(io.Discard)
+	err := fs.Parse(args)
+	opts.Once = opts.Once || subcommand
+	if err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+
+	opts.explicit = make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		if key, ok := flagSettings[f.Name]; ok {
+			opts.explicit[key] = true
+		}
+	})
+	return opts, nil
+}
+
+func newFlagSet(opts *Options) *flag.FlagSet {
+	fs := flag.NewFlagSet("multiview-monitor", flag.ContinueOnError)
+	fs.StringVar(&opts.File, "config", "", "Path to configuration file")
+	fs.StringVar(&opts.File, "f", "", "Path to configuration file (short)")
+	fs.BoolVar(&opts.GenerateConfig, "generate-config", false, "Generate default configuration file")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "Print the effective configuration and where each value comes from")
+	fs.BoolVar(&opts.CheckConfig, "check-config", false, "Check the configuration, list all problems and exit")
+	fs.BoolVar(&opts.Once, "once", false, "Collect once, print the channel status and exit with 0 OK, 1 WARNING or 2 CRITICAL")
+	fs.StringVar(&opts.Output, "output", "table", "Status output format: table, json, yaml or csv")
+	fs.StringVar(&opts.Output, "o", "table", "Status output format (short)")
+	fs.StringVar(&opts.HLSPath, "hls-path", "", "Base path for HLS package directories")
+	fs.StringVar(&opts.HLSPath, "p", "", "Base path for HLS package directories (short)")
+	fs.IntVar(&opts.ChannelCount, "channels", 0, "Number of channels to monitor")
+	fs.IntVar(&opts.ChannelCount, "c", 0, "Number of channels to monitor (short)")
+	fs.IntVar(&opts.StartPort, "start-port", 0, "Starting port number for FFmpeg processes")
+	fs.IntVar(&opts.StartPort, "s", 0, "Starting port number for FFmpeg processes (short)")
+	fs.StringVar(&opts.MetricsAddr, "metrics-addr", "", "Serve Prometheus metrics at /metrics on this address, e.g. :9273")
+	fs.StringVar(&opts.APIAddr, "api-addr", "", "Serve the JSON API at /api/v1/ on this address, e.g. :8080")
+	fs.StringVar(&opts.WebAddr, "web-addr", "", "Serve the web dashboard at / on this address, e.g. :8080")
+	fs.BoolVar(&opts.Headless, "headless", false, "Run without the terminal UI, serving only the HTTP endpoints")
+	fs.BoolVar(&opts.Help, "help", false, "Show help message")
+	fs.BoolVar(&opts.Help, "h", false, "Show help message (short)")
+	return fs
+}
+
+// Load builds a validated configuration from the layers selected by opts:
+// defaults, system file, user file, config file, environment and flags. It
+// neither prints nor exits, so several configurations can be loaded side by
+// side.
+func Load(opts Options) (*Config, error) {
+	cfg := DefaultConfig()
+	cfg.options = opts
+	cfg.sources = make(map[string]Source)
+	cfg.positions = make(map[string]Source)
+
+	for _, layer := range []struct{ name, path string }{
+		{LayerSystem, opts.SystemFile},
+		{LayerUser, opts.UserFile},
+	} {
+		if layer.path == "" || !exists(layer.path) {
+			continue
+		}
+		if err := cfg.applyFile(layer.name, layer.path); err != nil {
+			return nil, fmt.Errorf("loading %s config file %s: %w", layer.name, layer.path, err)
+		}
+	}
+
+	file := opts.File
+	if file == "" {
+		for _, path := range opts.SearchPaths {
+			if exists(path) {
+				file = path
+				break
+			}
+		}
+	}
+	if file != "" {
+		if err := cfg.applyFile(LayerFile, file); err != nil {
+			return nil, fmt.Errorf("loading config file %s: %w", file, err)
+		}
+	}
+
+	cfg.applyEnv(opts.Env)
+
+	// Command line options override everything else
+	if opts.HLSPath != "" || opts.explicit["hls.base_path"] {
+		cfg.HLS.BasePath = opts.HLSPath
+		cfg.sources["hls.base_path"] = Source{Layer: LayerFlag, Origin: "--hls-path"}
+	}
+	if opts.ChannelCount > 0 || opts.explicit["channels.count"] {
+		cfg.Channels.Count = opts.ChannelCount
+		cfg.sources["channels.count"] = Source{Layer: LayerFlag, Origin: "--channels"}
+	}
+	if opts.StartPort > 0 || opts.explicit["ffmpeg.start_port"] {
+		cfg.FFmpeg.StartPort = opts.StartPort
+		cfg.sources["ffmpeg.start_port"] = Source{Layer: LayerFlag, Origin: "--start-port"}
+	}
+	if opts.MetricsAddr != "" || opts.explicit["http.metrics_addr"] {
+		cfg.HTTP.MetricsAddr = opts.MetricsAddr
+		cfg.sources["http.metrics_addr"] = Source{Layer: LayerFlag, Origin: "--metrics-addr"}
+	}
+	if opts.APIAddr != "" || opts.explicit["http.api_addr"] {
+		cfg.HTTP.APIAddr = opts.APIAddr
+		cfg.sources["http.api_addr"] = Source{Layer: LayerFlag, Origin: "--api-addr"}
+	}
+	if opts.WebAddr != "" || opts.explicit["http.web_addr"] {
+		cfg.HTTP.WebAddr = opts.WebAddr
+		cfg.sources["http.web_addr"] = Source{Layer: LayerFlag, Origin: "--web-addr"}
+	}
+
+	// A channel list or discovery without a count only monitors the listed
+	// or discovered channels
+	if _, set := cfg.sources["channels.count"]; !set && (len(cfg.Channels.List) > 0 || cfg.Channels.Discovery != "off") {
+		cfg.Channels.Count = 0
+		cfg.countCleared = true
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Files returns the loaded config files, lowest layer first.
+func (c *Config) Files() []string {
+	return c.files
+}
+
+// Reload loads the configuration again with the same options. c itself is
+// left untouched, so callers keep using it if the new one is invalid.
+func (c *Config) Reload() (*Config, error) {
+	if len(c.files) == 0 {
+		return nil, errors.New("no configuration file loaded")
+	}
+	return Load(c.options)
+}
+
+// PrintHelp writes the usage message.
+func PrintHelp(w io.Writer) {
+	app := DefaultConfig().App
+	program := filepath.Base(os.Args[0])
+
+	fmt.Fprintf(w, "%s - %s\n", app.Name, app.Description)
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintf(w, "  %s [options]\n", program)
+	fmt.Fprintf(w, "  %s status [options]    Print the channel status once and exit\n", program)
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Configuration:")
+	fmt.Fprintln(w, "  -f, --config string        Path to configuration file")
+	fmt.Fprintln(w, "  --generate-config          Generate default configuration file")
+	fmt.Fprintln(w, "  --check-config             Check the configuration and list all problems")
+	fmt.Fprintln(w, "  --print-config             Print the effective configuration and its sources")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Status (for cron and monitoring systems):")
+	fmt.Fprintln(w, "  --once                     Collect once, print the channel status and exit")
+	fmt.Fprintln(w, "  -o, --output string        Status format: table, json, yaml or csv (default table)")
+	fmt.Fprintln(w, "  Exit codes: 0 OK, 1 WARNING, 2 CRITICAL, 3 UNKNOWN (configuration or usage error)")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Daemon:")
+	fmt.Fprintln(w, "  --metrics-addr string      Serve Prometheus metrics at /metrics, e.g. :9273")
+	fmt.Fprintln(w, "  --api-addr string          Serve the read-only JSON API at /api/v1/, e.g. :8080")
+	fmt.Fprintln(w, "  --web-addr string          Serve the web dashboard at /, e.g. :8080")
+	fmt.Fprintln(w, "  --headless                 Run without the terminal UI until SIGINT or SIGTERM")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Options (override config file):")
+	fs := newFlagSet(&Options{})
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Examples:")
+	fmt.Fprintf(w, "  %s --generate-config\n", program)
+	fmt.Fprintf(w, "  %s -f myconfig.yaml\n", program)
+	fmt.Fprintf(w, "  %s -f myconfig.yaml -c 12 -p /custom/path\n", program)
+	fmt.Fprintf(w, "  %s -p /data/hls -c 12 -s 9001\n", program)
+	fmt.Fprintf(w, "  %s --print-config\n", program)
+	fmt.Fprintf(w, "  %s --check-config -f myconfig.yaml\n", program)
+	fmt.Fprintf(w, "  %s status -o json\n", program)
+	fmt.Fprintf(w, "  %s --headless --metrics-addr :9273 --web-addr :8080\n", program)
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Configuration layers (later ones override earlier ones):")
+	fmt.Fprintln(w, "  1. Built-in defaults")
+	fmt.Fprintln(w, "  2. /etc/multiview-monitor.yaml")
+	fmt.Fprintln(w, "  3. ~/.multiview-monitor.yaml")
+	fmt.Fprintln(w, "  4. -f file, or the first of ./multiview-monitor.yaml and ./configs/multiview-monitor.yaml")
+	fmt.Fprintln(w, "  5. MULTIVIEW_* environment variables, e.g. MULTIVIEW_HLS_BASE_PATH=/data/hls")
+	fmt.Fprintln(w, "  6. Command line options")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Keyboard shortcuts:")
+	fmt.Fprintln(w, "  Tab       - Switch between FFmpeg and HLS panels")
+	fmt.Fprintln(w, "  ↑/↓       - Navigate channels")
+	fmt.Fprintln(w, "  Enter     - View channel details")
+	fmt.Fprintln(w, "  Esc       - Return to main view")
+	fmt.Fprintln(w, "  p         - Pause/resume refresh")
+	fmt.Fprintln(w, "  q         - Quit")
+}
+
+// Summary describes the effective settings in a few lines.
+func (c *Config) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Starting %s v%s with:\n", c.App.Name, c.App.Version)
+	if len(c.files) > 0 {
+		fmt.Fprintf(&b, "  Config Files: %s\n", strings.Join(c.files, ", "))
+	}
+	fmt.Fprintf(&b, "  HLS Base Path: %s\n", c.HLS.BasePath)
+	fmt.Fprintf(&b, "  HLS Watch Mode: %s\n", c.HLS.WatchMode)
+	fmt.Fprintf(&b, "  Channels: %d (%d generated, %d listed, discovery %s)\n", len(c.ChannelList()), c.Channels.Count, len(c.Channels.List), c.Channels.Discovery)
+	fmt.Fprintf(&b, "  Start Port: %d\n", c.FFmpeg.StartPort)
+	fmt.Fprintf(&b, "  Match Strategy: %s\n", c.FFmpeg.MatchStrategy)
+	intervals := c.RefreshIntervals()
+	fmt.Fprintf(&b, "  Refresh Intervals: process %s, hls scan %s, playlist %s, ui %s\n",
+		intervals.Process, intervals.HLSScan, intervals.Playlist, intervals.UI)
+	return b.String()
+}
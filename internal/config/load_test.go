@@ -0,0 +1,181 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadTest loads a config file with content, the environment env and the
+// command line args, without any system, user or working directory file.
+func loadTest(t *testing.T, content string, env []string, args ...string) (*Config, error) {
+	t.Helper()
+	opts, err := ParseFlags(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.SystemFile, opts.UserFile, opts.SearchPaths = "", "", nil
+	opts.Env = env
+	if content != "" {
+		opts.File = filepath.Join(t.TempDir(), "monitor.yaml")
+		if err := os.WriteFile(opts.File, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return Load(opts)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	const file = "hls:\n  base_path: /from/file\n  warn_factor: 2.5\nffmpeg:\n  start_port: 7000\n"
+	env := []string{"MULTIVIEW_HLS_BASE_PATH=/from/env", "MULTIVIEW_FFMPEG_START_PORT=7100", "PATH=/usr/bin"}
+
+	tests := []struct {
+		name     string
+		content  string
+		env      []string
+		args     []string
+		basePath string
+		port     int
+		source   string
+	}{
+		{"default", "", nil, nil, "/output", 8001, LayerDefault},
+		{"file", file, nil, nil, "/from/file", 7000, LayerFile},
+		{"env", file, env, nil, "/from/env", 7100, LayerEnv},
+		{"flag", file, env, []string{"-p", "/from/flag", "--start-port", "7200"}, "/from/flag", 7200, LayerFlag},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadTest(t, tt.content, tt.env, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.HLS.BasePath != tt.basePath || cfg.FFmpeg.StartPort != tt.port {
+				t.Errorf("base path %s, start port %d, want %s and %d", cfg.HLS.BasePath, cfg.FFmpeg.StartPort, tt.basePath, tt.port)
+			}
+			for _, key := range []string{"hls.base_path", "ffmpeg.start_port"} {
+				if layer := cfg.Source(key).Layer; layer != tt.source {
+					t.Errorf("%s from %s, want %s", key, layer, tt.source)
+				}
+			}
+			// Settings no higher layer touches keep their own layer
+			if tt.content != "" && (cfg.HLS.WarnFactor != 2.5 || cfg.Source("hls.warn_factor").Layer != LayerFile) {
+				t.Errorf("hls.warn_factor = %v from %s, want 2.5 from the file", cfg.HLS.WarnFactor, cfg.Source("hls.warn_factor"))
+			}
+		})
+	}
+}
+
+func TestLoadZeroValuesOverride(t *testing.T) {
+	const file = "ui:\n  fullscreen: true\nffmpeg:\n  flap_threshold: 5\nhttp:\n  metrics_addr: \":9273\"\n"
+
+	cfg, err := loadTest(t, "ui:\n  fullscreen: false\nffmpeg:\n  flap_threshold: 0\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.UI.Fullscreen || cfg.FFmpeg.FlapThreshold != 0 {
+		t.Errorf("file: fullscreen %v, flap threshold %d, want false and 0 over the defaults", cfg.UI.Fullscreen, cfg.FFmpeg.FlapThreshold)
+	}
+
+	cfg, err = loadTest(t, file, []string{"MULTIVIEW_UI_FULLSCREEN=false", "MULTIVIEW_FFMPEG_FLAP_THRESHOLD=0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.UI.Fullscreen || cfg.FFmpeg.FlapThreshold != 0 {
+		t.Errorf("env: fullscreen %v, flap threshold %d, want false and 0 over the file", cfg.UI.Fullscreen, cfg.FFmpeg.FlapThreshold)
+	}
+
+	cfg, err = loadTest(t, file, []string{"MULTIVIEW_HTTP_METRICS_ADDR=:9300", "MULTIVIEW_CHANNELS_DISCOVERY=hls"}, "--metrics-addr", "", "--channels", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.HTTP.MetricsAddr != "" || cfg.Source("http.metrics_addr").Layer != LayerFlag {
+		t.Errorf("metrics addr %q from %s, want the empty flag", cfg.HTTP.MetricsAddr, cfg.Source("http.metrics_addr"))
+	}
+	if cfg.Channels.Count != 0 || cfg.Source("channels.count").Layer != LayerFlag {
+		t.Errorf("channel count %d from %s, want 0 from the flag", cfg.Channels.Count, cfg.Source("channels.count"))
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	cfg, err := loadTest(t, "", []string{
+		"MULTIVIEW_HLS_BASE_PATH=/data/hls",
+		"MULTIVIEW_HLS_STALE_FACTOR=4.5",
+		"MULTIVIEW_INTERVALS_PROCESS=500ms",
+		"MULTIVIEW_UI_FULLSCREEN=1",
+		"MULTIVIEW_CHANNELS_COUNT=0",
+		"MULTIVIEW_CHANNELS_LIST=[{id: news, port: 9001}, {id: sports, port: 9002, path: sport}]",
+		"MULTIVIEW_UNKNOWN_SETTING=ignored",
+		"HLS_BASE_PATH=/not/prefixed",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.HLS.BasePath != "/data/hls" || cfg.HLS.StaleFactor != 4.5 || cfg.Intervals.Process != 500*time.Millisecond || !cfg.UI.Fullscreen {
+		t.Errorf("hls = %+v, intervals = %+v, ui = %+v", cfg.HLS, cfg.Intervals, cfg.UI)
+	}
+	list := cfg.Channels.List
+	if len(list) != 2 || list[0].ID != "news" || list[0].Port != 9001 || list[1].Path != "sport" {
+		t.Errorf("channels.list = %+v", list)
+	}
+	if source := cfg.Source("channels.list"); source.Layer != LayerEnv || source.Origin != "MULTIVIEW_CHANNELS_LIST" {
+		t.Errorf("channels.list from %s", source)
+	}
+}
+
+func TestPrintEffectiveSources(t *testing.T) {
+	cfg, err := loadTest(t, "hls:\n  base_path: /from/file\n", []string{"MULTIVIEW_UI_THEME=light"}, "-c", "16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	cfg.PrintEffective(&buf)
+
+	lines := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		key, rest, _ := strings.Cut(line, " ")
+		lines[key] = strings.Join(strings.Fields(rest), " ")
+	}
+	want := map[string]string{
+		"hls.base_path":     `"/from/file" file ` + cfg.Files()[0] + ":2:3",
+		"ui.theme":          `"light" env MULTIVIEW_UI_THEME`,
+		"channels.count":    "16 flag --channels",
+		"ffmpeg.start_port": "8001 default",
+	}
+	for key, line := range want {
+		if lines[key] != line {
+			t.Errorf("%s: %q, want %q", key, lines[key], line)
+		}
+	}
+}
+
+func TestLoadReportsAllEnvProblems(t *testing.T) {
+	_, err := loadTest(t, "ui:\n  refresh_interval: often\n", []string{
+		"MULTIVIEW_CHANNELS_COUNT=many",
+		"MULTIVIEW_UI_FULLSCREEN=maybe",
+		"MULTIVIEW_CHANNELS_LIST=[{id: news",
+		"MULTIVIEW_HLS_BASE_PATH=/data/hls",
+	})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Load() = %v, want a ValidationError", err)
+	}
+	// File problems first, then variables in the order of the settings
+	want := []string{
+		"monitor.yaml:2: cannot unmarshal",
+		"MULTIVIEW_CHANNELS_COUNT: invalid integer",
+		"MULTIVIEW_CHANNELS_LIST: invalid YAML value",
+		"MULTIVIEW_UI_FULLSCREEN: invalid boolean",
+	}
+	if len(validationErr.Problems) != len(want) {
+		t.Fatalf("problems = %q, want %d", validationErr.Problems, len(want))
+	}
+	for i, problem := range validationErr.Problems {
+		if !strings.Contains(problem, want[i]) {
+			t.Errorf("problem %d = %q, want it to mention %q", i, problem, want[i])
+		}
+	}
+}
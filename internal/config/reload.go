@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// WatchConfig reloads cfg whenever one of its config files changes, checked
+// every interval, or the process receives SIGHUP, and reports every attempt to
+// onReload. Later attempts reload the last valid configuration. The
+// returned function stops watching.
+func WatchConfig(cfg *Config, interval time.Duration, onReload func(*Config, error)) func() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := fileStamps(cfg.Files())
+		for {
+			select {
+			case <-stop:
+				return
+			case <-hup:
+			case <-ticker.C:
+				stamps := fileStamps(cfg.Files())
+				// Skip the moment an editor has removed a file before
+				// writing the new one
+				if stamps == last || strings.Contains(stamps, missingStamp) {
+					continue
+				}
+			}
+			last = fileStamps(cfg.Files())
+
+			reloaded, err := cfg.Reload()
+			if err == nil {
+				cfg = reloaded
+			}
+			onReload(reloaded, err)
+		}
+	}()
+
+	return func() {
+		signal.Stop(hup)
+		close(stop)
+		<-done
+	}
+}
+
+const missingStamp = " missing\n"
+
+// fileStamps sums up the modification time and size of files, to notice
+// when any of them changes.
+func fileStamps(files []string) string {
+	var b strings.Builder
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&b, "%s%s", path, missingStamp)
+			continue
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", path, info.ModTime().UnixNano(), info.Size())
+	}
+	return b.String()
+}
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError lists every problem found in a configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0]
+	}
+	return fmt.Sprintf("%d problems: %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+// MaxPort is the highest TCP/UDP port.
+const MaxPort = 65535
+
+// Validate checks a configuration before it is used and reports all
+// problems at once, each prefixed with where the setting came from.
+func (c *Config) Validate() error {
+	v := validator{cfg: c, problems: append([]string(nil), c.problems...)}
+
+	if c.Channels.Count < 0 || c.Channels.Count > 999 {
+		v.add("channels.count", "invalid channel count: %d (must be 0-999)", c.Channels.Count)
+	}
+	v.intVerb("channels.id_format", c.Channels.IDFormat)
+	v.intVerb("hls.channel_dir_pattern", c.HLS.ChannelDirPattern)
+
+	if c.FFmpeg.StartPort <= 0 || c.FFmpeg.StartPort > MaxPort {
+		v.add("ffmpeg.start_port", "invalid start port: %d (must be 1-%d)", c.FFmpeg.StartPort, MaxPort)
+	} else if c.FFmpeg.PortIncrement < 0 {
+		v.add("ffmpeg.port_increment", "port increment must not be negative: %d", c.FFmpeg.PortIncrement)
+	} else if c.Channels.Count > 0 {
+		last := c.FFmpeg.StartPort + (c.Channels.Count-1)*c.FFmpeg.PortIncrement
+		if last > MaxPort {
+			v.add("ffmpeg.start_port", "ports of %d channels from %d in steps of %d end at %d, above %d",
+				c.Channels.Count, c.FFmpeg.StartPort, c.FFmpeg.PortIncrement, last, MaxPort)
+		}
+	}
+
+	if c.HLS.BasePath == "" {
+		v.add("hls.base_path", "HLS base path cannot be empty")
+	}
+	if c.HLS.WarnFactor <= 0 || c.HLS.StaleFactor < c.HLS.WarnFactor {
+		v.add("hls.warn_factor", "invalid HLS health factors: warn %.1f, stale %.1f (stale must be >= warn > 0)", c.HLS.WarnFactor, c.HLS.StaleFactor)
+	}
+	switch c.HLS.WatchMode {
+	case "auto", "inotify", "poll":
+	default:
+		v.add("hls.watch_mode", "unknown HLS watch mode: %q (must be auto, inotify or poll)", c.HLS.WatchMode)
+	}
+	if c.HLS.RescanInterval <= 0 {
+		v.add("hls.rescan_interval", "HLS rescan interval must be positive: %d", c.HLS.RescanInterval)
+	}
+	if c.UI.RefreshInterval <= 0 {
+		v.add("ui.refresh_interval", "refresh interval must be positive: %d", c.UI.RefreshInterval)
+	}
+	for _, interval := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"intervals.process", c.Intervals.Process},
+		{"intervals.hls_scan", c.Intervals.HLSScan},
+		{"intervals.playlist", c.Intervals.Playlist},
+		{"intervals.ui", c.Intervals.UI},
+	} {
+		if interval.value != 0 && interval.value < MinInterval {
+			v.add(interval.key, "%s interval %s is below the minimum of %s", strings.TrimPrefix(interval.key, "intervals."), interval.value, MinInterval)
+		}
+	}
+	switch c.FFmpeg.MatchStrategy {
+	case "port", "output_path", "label":
+	case "regex":
+		re, err := regexp.Compile(c.FFmpeg.MatchRegex)
+		if err != nil {
+			v.add("ffmpeg.match_regex", "invalid match regex: %v", err)
+		} else if re.NumSubexp() == 0 {
+			v.add("ffmpeg.match_regex", "match regex must contain a capture group: %s", c.FFmpeg.MatchRegex)
+		}
+	default:
+		v.add("ffmpeg.match_strategy", "unknown match strategy: %q (must be port, output_path, regex or label)", c.FFmpeg.MatchStrategy)
+	}
+
+	v.address("http.metrics_addr", c.HTTP.MetricsAddr)
+	v.address("http.api_addr", c.HTTP.APIAddr)
+	v.address("http.web_addr", c.HTTP.WebAddr)
+
+	v.channelList()
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// Warnings checks the configuration against the machine it runs on and
+// points out defaults Load changed. These are not errors: the HLS base path
+// may only appear once ffmpeg writes to it or its storage is mounted, and the
+// monitor reports it as missing meanwhile.
+func (c *Config) Warnings() []string {
+	v := validator{cfg: c}
+	if c.HLS.BasePath != "" {
+		if info, err := os.Stat(c.HLS.BasePath); err != nil {
+			v.add("hls.base_path", "HLS base path %s does not exist", c.HLS.BasePath)
+		} else if !info.IsDir() {
+			v.add("hls.base_path", "HLS base path %s is not a directory", c.HLS.BasePath)
+		}
+	}
+	if c.countCleared {
+		key := "channels.list"
+		if len(c.Channels.List) == 0 {
+			key = "channels.discovery"
+		}
+		v.add(key, "channels.count is not set, so only listed and discovered channels are monitored instead of %d generated ones; set channels.count to keep them",
+			DefaultConfig().Channels.Count)
+	}
+	return v.problems
+}
+
+type validator struct {
+	cfg      *Config
+	problems []string
+}
+
+// add records a problem with the setting key.
+func (v *validator) add(key, format string, args ...any) {
+	v.problems = append(v.problems, v.cfg.location(key)+fmt.Sprintf(format, args...))
+}
+
+// intVerb checks that a channel number format takes exactly one integer.
+func (v *validator) intVerb(key, format string) {
+	ints, others := countVerbs(format)
+	if ints != 1 || others != 0 {
+		v.add(key, "%s %q must contain exactly one integer verb such as %%02d", key, format)
+	}
+}
+
+// address checks an optional host:port listen address.
+func (v *validator) address(key, addr string) {
+	if addr == "" {
+		return
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		v.add(key, "invalid listen address %q: %v", addr, err)
+		return
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > MaxPort {
+		v.add(key, "invalid listen address %q: port must be 0-%d", addr, MaxPort)
+	}
+}
+
+func (v *validator) channelList() {
+	c := v.cfg
+	for i, def := range c.Channels.List {
+		key := fmt.Sprintf("channels.list[%d]", i)
+		if def.ID == "" && (def.Number <= 0 || def.Number > c.Channels.Count) {
+			v.add(key, "%s: id is required unless number selects a generated channel", key)
+		}
+		for _, port := range def.ports() {
+			if port <= 0 || port > MaxPort {
+				v.add(key, "%s: invalid port %d (must be 1-%d)", key, port, MaxPort)
+			}
+		}
+		if def.WarnFactor < 0 || def.StaleFactor < 0 {
+			v.add(key, "%s: health factors must not be negative", key)
+		}
+	}
+
+	switch c.Channels.Discovery {
+	case "off", "hls", "ffmpeg", "both":
+	default:
+		v.add("channels.discovery", "unknown channel discovery mode: %q (must be off, hls, ffmpeg or both)", c.Channels.Discovery)
+	}
+
+	channels := c.ChannelList()
+	if len(channels) == 0 && c.Channels.Discovery == "off" {
+		v.add("channels.count", "no channels configured (set channels.count, channels.list or channels.discovery)")
+	}
+	seen := make(map[string]bool)
+	for _, ch := range channels {
+		if seen[ch.ID] {
+			v.add("channels", "duplicate channel id: %s", ch.ID)
+		}
+		seen[ch.ID] = true
+		if ch.WarnFactor <= 0 || ch.StaleFactor < ch.WarnFactor {
+			v.add("channels", "channel %s: invalid health factors: warn %.1f, stale %.1f (stale must be >= warn > 0)", ch.ID, ch.WarnFactor, ch.StaleFactor)
+		}
+	}
+}
+
+// countVerbs counts the integer and other verbs of a printf format.
+func countVerbs(format string) (ints, others int) {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		// Skip flags, width and precision
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789.", format[i]) >= 0 {
+			i++
+		}
+		if i == len(format) {
+			others++
+			break
+		}
+		switch format[i] {
+		case '%':
+		case 'd', 'x', 'X', 'o', 'O', 'b':
+			ints++
+		default:
+			others++
+		}
+	}
+	return ints, others
+}
+
+// location returns where key was set, e.g. "multiview-monitor.yaml:12:3: ",
+// or an empty string for defaults.
+func (c *Config) location(key string) string {
+	source, ok := c.sources[key]
+	if !ok {
+		source, ok = c.positions[key]
+	}
+	if !ok || source.Origin == "" {
+		return ""
+	}
+	if source.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d: ", source.Origin, source.Line, source.Column)
+	}
+	return source.Origin + ": "
+}
+
+// checkFields reports every mapping key below node that has no field in t.
+func checkFields(file, path string, node *yaml.Node, t reflect.Type, problems *[]string) {
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			field, ok := fieldByTag(t, keyNode.Value)
+			if !ok {
+				section := "top level"
+				if path != "" {
+					section = path
+				}
+				*problems = append(*problems, fmt.Sprintf("%s:%d:%d: unknown field %q in %s",
+					file, keyNode.Line, keyNode.Column, keyNode.Value, section))
+				continue
+			}
+			checkFields(file, joinKey(path, keyNode.Value), valueNode, field.Type, problems)
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			checkFields(file, fmt.Sprintf("%s[%d]", path, i), item, t.Elem(), problems)
+		}
+	}
+}
+
+func fieldByTag(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() && strings.Split(field.Tag.Get("yaml"), ",")[0] == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMissingBasePathIsOnlyAWarning(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.HLS.BasePath = filepath.Join(t.TempDir(), "not-yet-created")
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want nil for a missing base path", err)
+	}
+	warnings := cfg.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "does not exist") {
+		t.Fatalf("Warnings() = %q, want one about the missing base path", warnings)
+	}
+}
+
+func TestEmptyBasePathIsAnError(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.HLS.BasePath = ""
+
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "cannot be empty") {
+		t.Fatalf("Validate() = %v, want an empty base path error", err)
+	}
+}
+
+func TestClearedCountIsAWarning(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		warn    string
+	}{
+		{"list", "channels:\n  list:\n    - id: news\n      port: 9001\n", "monitor.yaml:4:3: channels.count is not set"},
+		{"discovery", "channels:\n  discovery: hls\n", "monitor.yaml:4:3: channels.count is not set"},
+		{"count set", "channels:\n  count: 24\n  discovery: hls\n", ""},
+		{"generated only", "channels:\n  count: 4\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadTest(t, "hls:\n  base_path: "+t.TempDir()+"\n"+tt.content, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			warnings := cfg.Warnings()
+			switch {
+			case tt.warn == "" && len(warnings) > 0:
+				t.Errorf("Warnings() = %q, want none", warnings)
+			case tt.warn != "" && (len(warnings) != 1 || !strings.Contains(warnings[0], tt.warn)):
+				t.Errorf("Warnings() = %q, want one containing %q", warnings, tt.warn)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package monitor
+
+import (
+	"monitorMultiview/internal/config"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Snapshot is the state of every channel at one point in time. A published
+// snapshot is never modified again, neither by the collector nor by
+// consumers, so it can be read from any goroutine without locking.
+type Snapshot struct {
+	Channels  []config.Channel
+	Processes map[string][]*FFmpegProcess
+	Histories map[string]*ProcessHistory
+	Packages  map[string]*HLSPackage
+
+	// ProcessesTaken and PackagesTaken are zero until the first sample
+	ProcessesTaken time.Time
+	PackagesTaken  time.Time
+}
+
+// Channel returns the configuration of channelID.
+func (s *Snapshot) Channel(channelID string) (config.Channel, bool) {
+	for _, ch := range s.Channels {
+		if ch.ID == channelID {
+			return ch, true
+		}
+	}
+	return config.Channel{}, false
+}
+
+// ChannelProcesses returns every process serving channelID, primary first.
+func (s *Snapshot) ChannelProcesses(channelID string) []*FFmpegProcess {
+	return s.Processes[channelID]
+}
+
+// History returns the restart history of channelID, or nil.
+func (s *Snapshot) History(channelID string) *ProcessHistory {
+	return s.Histories[channelID]
+}
+
+// Package returns the HLS package of channelID, or nil.
+func (s *Snapshot) Package(channelID string) *HLSPackage {
+	return s.Packages[channelID]
+}
+
+// RunningChannels counts the channels with at least one process.
+func (s *Snapshot) RunningChannels() int {
+	running := 0
+	for _, ch := range s.Channels {
+		if len(s.Processes[ch.ID]) > 0 {
+			running++
+		}
+	}
+	return running
+}
+
+// Updated returns when the newest data in the snapshot was sampled.
+func (s *Snapshot) Updated() time.Time {
+	if s.PackagesTaken.After(s.ProcessesTaken) {
+		return s.PackagesTaken
+	}
+	return s.ProcessesTaken
+}
+
+type processSample struct {
+	processes map[string][]*FFmpegProcess
+	histories map[string]*ProcessHistory
+	taken     time.Time
+}
+
+type packageSample struct {
+	packages map[string]*HLSPackage
+	taken    time.Time
+}
+
+// CollectorIntervals sets how often each part of the collector runs.
+type CollectorIntervals struct {
+	Process   time.Duration
+	Directory time.Duration
+	Playlist  time.Duration
+}
+
+// Collector samples the monitors on background goroutines and publishes the
+// results as snapshots, so readers never trigger any I/O themselves.
+type Collector struct {
+	ffmpegMonitor *FFmpegMonitor
+	hlsMonitor    *HLSMonitor
+	intervals     atomic.Pointer[CollectorIntervals]
+	cfg           atomic.Pointer[config.Config]
+	discovery     *Discovery
+
+	processes atomic.Pointer[processSample]
+	packages  atomic.Pointer[packageSample]
+	current   atomic.Pointer[Snapshot]
+
+	mu          sync.Mutex
+	subscribers map[chan *Snapshot]struct{}
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// NewCollector samples both monitors at the intervals of cfg.
+func NewCollector(cfg *config.Config, ffmpegMonitor *FFmpegMonitor, hlsMonitor *HLSMonitor) *Collector {
+	c := &Collector{
+		ffmpegMonitor: ffmpegMonitor,
+		hlsMonitor:    hlsMonitor,
+		subscribers:   make(map[chan *Snapshot]struct{}),
+		stop:          make(chan struct{}),
+	}
+	c.cfg.Store(cfg)
+	c.SetIntervals(intervalsOf(cfg))
+	c.current.Store(c.buildSnapshot())
+	return c
+}
+
+func intervalsOf(cfg *config.Config) CollectorIntervals {
+	intervals := cfg.RefreshIntervals()
+	return CollectorIntervals{
+		Process:   intervals.Process,
+		Directory: intervals.HLSScan,
+		Playlist:  intervals.Playlist,
+	}
+}
+
+// Config returns the configuration the collector currently runs with.
+func (c *Collector) Config() *config.Config {
+	return c.cfg.Load()
+}
+
+// ApplyConfig hands a reloaded configuration to the monitors and switches
+// to its intervals. State is kept for channels whose ID did not change.
+func (c *Collector) ApplyConfig(cfg *config.Config) {
+	c.cfg.Store(cfg)
+	c.SetIntervals(intervalsOf(cfg))
+	c.ffmpegMonitor.ApplyConfig(cfg)
+	c.hlsMonitor.ApplyConfig(cfg)
+	if c.discovery != nil {
+		c.discovery.ApplyConfig(cfg)
+	}
+}
+
+// SetIntervals changes the sampling intervals. Each sampler switches after
+// its next sample.
+func (c *Collector) SetIntervals(intervals CollectorIntervals) {
+	c.intervals.Store(&intervals)
+}
+
+// SetDiscovery makes the collector and both monitors follow the channels
+// found by discovery, which is then rerun at the directory interval. It
+// must be called before Start.
+func (c *Collector) SetDiscovery(discovery *Discovery) {
+	c.discovery = discovery
+	c.ffmpegMonitor.SetChannelSource(discovery)
+	c.hlsMonitor.SetChannelSource(discovery)
+}
+
+// Start launches the sampling goroutines for processes, HLS directories and
+// playlists. Each runs right away and then at its own interval until Stop
+// is called.
+func (c *Collector) Start() {
+	if c.discovery != nil {
+		// Find the initial channels before the first samples
+		c.discovery.Discover()
+		c.run(c.directoryInterval, c.discovery.Discover)
+	}
+	c.run(c.processInterval, c.sampleProcesses)
+	c.run(c.directoryInterval, c.hlsMonitor.ScanDirectories)
+	c.run(c.playlistInterval, c.samplePackages)
+}
+
+// CollectOnce runs discovery, the process and the HLS samplers once on the
+// calling goroutine and returns the resulting snapshot, for callers that
+// need a single reading instead of Start.
+func (c *Collector) CollectOnce() *Snapshot {
+	if c.discovery != nil {
+		c.discovery.Discover()
+	}
+	c.sampleProcesses()
+	c.hlsMonitor.ScanDirectories()
+	c.samplePackages()
+	return c.Snapshot()
+}
+
+func (c *Collector) channelList() []config.Channel {
+	if c.discovery != nil {
+		return c.discovery.Channels()
+	}
+	return c.cfg.Load().ChannelList()
+}
+
+func (c *Collector) processInterval() time.Duration   { return c.intervals.Load().Process }
+func (c *Collector) directoryInterval() time.Duration { return c.intervals.Load().Directory }
+func (c *Collector) playlistInterval() time.Duration  { return c.intervals.Load().Playlist }
+
+// Stop ends sampling and waits for in-flight samples to finish.
+func (c *Collector) Stop() {
+	close(c.stop)
+	c.wg.Wait()
+}
+
+func (c *Collector) run(interval func() time.Duration, sample func()) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		timer := time.NewTimer(interval())
+		defer timer.Stop()
+
+		for {
+			sample()
+			select {
+			case <-c.stop:
+				return
+			case <-timer.C:
+			}
+			timer.Reset(interval())
+		}
+	}()
+}
+
+// Snapshot returns the latest published snapshot. It is never nil; before
+// the first sample it only lists the configured channels.
+func (c *Collector) Snapshot() *Snapshot {
+	return c.current.Load()
+}
+
+// Subscribe returns a channel receiving every new snapshot and a function
+// to cancel the subscription. A slow subscriber only gets the newest
+// snapshot instead of blocking the collector.
+func (c *Collector) Subscribe() (<-chan *Snapshot, func()) {
+	ch := make(chan *Snapshot, 1)
+
+	c.mu.Lock()
+	c.subscribers[ch] = struct{}{}
+	c.mu.Unlock()
+
+	cancel := func() {
+		c.mu.Lock()
+		delete(c.subscribers, ch)
+		c.mu.Unlock()
+	}
+	return ch, cancel
+}
+
+func (c *Collector) sampleProcesses() {
+	c.ffmpegMonitor.Refresh()
+
+	sample := &processSample{
+		processes: make(map[string][]*FFmpegProcess),
+		histories: make(map[string]*ProcessHistory),
+		taken:     time.Now(),
+	}
+	for _, ch := range c.channelList() {
+		if procs := c.ffmpegMonitor.GetChannelProcesses(ch.ID); len(procs) > 0 {
+			sample.processes[ch.ID] = procs
+		}
+		if history := c.ffmpegMonitor.GetChannelHistory(ch.ID); history != nil {
+			sample.histories[ch.ID] = history
+		}
+	}
+	c.processes.Store(sample)
+	c.publish()
+}
+
+func (c *Collector) samplePackages() {
+	sample := &packageSample{
+		packages: make(map[string]*HLSPackage),
+		taken:    time.Now(),
+	}
+	c.hlsMonitor.EvaluatePackages()
+	for _, pkg := range c.hlsMonitor.Packages() {
+		sample.packages[pkg.ChannelID] = pkg
+	}
+	c.packages.Store(sample)
+	c.publish()
+}
+
+// publish combines the latest samples into a new snapshot. The mutex keeps
+// snapshots from concurrent samplers in order for subscribers.
+func (c *Collector) publish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snapshot := c.buildSnapshot()
+	c.current.Store(snapshot)
+
+	for ch := range c.subscribers {
+		// Replace an unread snapshot with the newer one
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- snapshot
+	}
+}
+
+func (c *Collector) buildSnapshot() *Snapshot {
+	snapshot := &Snapshot{
+		Channels:  c.channelList(),
+		Processes: map[string][]*FFmpegProcess{},
+		Histories: map[string]*ProcessHistory{},
+		Packages:  map[string]*HLSPackage{},
+	}
+	if sample := c.processes.Load(); sample != nil {
+		snapshot.Processes = sample.processes
+		snapshot.Histories = sample.histories
+		snapshot.ProcessesTaken = sample.taken
+	}
+	if sample := c.packages.Load(); sample != nil {
+		snapshot.Packages = sample.packages
+		snapshot.PackagesTaken = sample.taken
+	}
+	return snapshot
+}
@@ -0,0 +1,257 @@
+package monitor
+
+import (
+	"log"
+	"monitorMultiview/internal/config"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Discovery modes
+const (
+	DiscoverOff    = "off"
+	DiscoverHLS    = "hls"
+	DiscoverFFmpeg = "ffmpeg"
+	DiscoverBoth   = "both"
+)
+
+// ChannelSource supplies the channels to monitor.
+type ChannelSource interface {
+	Channels() []config.Channel
+}
+
+// VanishedRetention is how long a discovered channel stays listed, marked
+// as vanished, after it disappears.
+const VanishedRetention = 10 * time.Minute
+
+// Discovery finds channels on its own: directories below the HLS base path
+// that hold playlists, and directories running ffmpeg processes write HLS
+// to. Discovered channels are listed after the configured ones and are kept
+// for VanishedRetention, marked as vanished, once they disappear.
+type Discovery struct {
+	mode     string
+	basePath string
+	scanner  *ProcScanner
+
+	mu    sync.Mutex
+	cfg   *config.Config
+	found map[string]*config.Channel // by directory
+}
+
+// NewDiscovery discovers channels in the mode and below the HLS base path
+// of cfg, finding ffmpeg processes with scanner. Pass the scanner of the
+// FFmpegMonitor so both read the same proc root.
+func NewDiscovery(cfg *config.Config, scanner *ProcScanner) *Discovery {
+	return &Discovery{
+		mode:     cfg.Channels.Discovery,
+		basePath: absPath(cfg.HLS.BasePath),
+		scanner:  scanner,
+		cfg:      cfg,
+		found:    make(map[string]*config.Channel),
+	}
+}
+
+// ApplyConfig switches to the configured channels of a reloaded
+// configuration. The mode and base path stay as they were.
+func (d *Discovery) ApplyConfig(cfg *config.Config) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cfg = cfg
+}
+
+func (d *Discovery) config() *config.Config {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cfg
+}
+
+// Discover rescans the base path and the running processes.
+func (d *Discovery) Discover() {
+	cfg := d.config()
+	seen := make(map[string]*config.Channel)
+	if d.mode == DiscoverHLS || d.mode == DiscoverBoth {
+		for _, dir := range d.playlistDirs() {
+			seen[dir] = d.newChannel(cfg, dir, DiscoverHLS)
+		}
+	}
+	if d.mode == DiscoverFFmpeg || d.mode == DiscoverBoth {
+		for dir, ports := range d.ffmpegOutputDirs() {
+			ch, ok := seen[dir]
+			if ok {
+				ch.Discovered = DiscoverBoth
+			} else {
+				ch = d.newChannel(cfg, dir, DiscoverFFmpeg)
+				seen[dir] = ch
+			}
+			if len(ports) > 0 {
+				ch.Ports = ports
+				ch.Port = ports[0]
+			}
+		}
+	}
+
+	configured := make(map[string]bool)
+	for _, ch := range cfg.ChannelList() {
+		configured[absPath(ch.Path)] = true
+		configured[ch.ID] = true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for dir, ch := range seen {
+		if configured[dir] || configured[ch.ID] {
+			continue
+		}
+		if _, known := d.found[dir]; !known {
+			log.Printf("discovered channel %s at %s (%s)", ch.ID, dir, ch.Discovered)
+		}
+		d.found[dir] = ch
+	}
+	now := time.Now()
+	for dir, ch := range d.found {
+		if _, ok := seen[dir]; ok {
+			continue
+		}
+		switch {
+		case !ch.Vanished:
+			log.Printf("discovered channel %s vanished", ch.ID)
+			ch.Vanished = true
+			ch.VanishedAt = now
+		case now.Sub(ch.VanishedAt) >= VanishedRetention:
+			log.Printf("discovered channel %s removed after vanishing at %s", ch.ID, ch.VanishedAt.Format(time.RFC3339))
+			delete(d.found, dir)
+		}
+	}
+}
+
+// Channels returns the configured channels followed by the discovered ones
+// sorted by ID.
+func (d *Discovery) Channels() []config.Channel {
+	d.mu.Lock()
+	channels := d.cfg.ChannelList()
+	discovered := make([]config.Channel, 0, len(d.found))
+	for _, ch := range d.found {
+		discovered = append(discovered, *ch)
+	}
+	d.mu.Unlock()
+
+	sort.Slice(discovered, func(i, j int) bool {
+		return discovered[i].ID < discovered[j].ID
+	})
+	return append(channels, discovered...)
+}
+
+// newChannel describes a discovered directory. The ID is the directory name
+// for direct children of the base path and the relative path otherwise.
+func (d *Discovery) newChannel(cfg *config.Config, dir, source string) *config.Channel {
+	id := filepath.Base(dir)
+	if rel, err := filepath.Rel(d.basePath, dir); err == nil && !strings.HasPrefix(rel, "..") {
+		id = filepath.ToSlash(rel)
+	}
+	return &config.Channel{
+		ID:          id,
+		Name:        id,
+		Path:        dir,
+		WarnFactor:  cfg.HLS.WarnFactor,
+		StaleFactor: cfg.HLS.StaleFactor,
+		Discovered:  source,
+	}
+}
+
+// playlistDirs lists the directories directly below the base path with a
+// playlist in them or in one of their subdirectories, e.g. per-rendition
+// directories.
+func (d *Discovery) playlistDirs() []string {
+	entries, err := os.ReadDir(d.basePath)
+	if err != nil {
+		return nil
+	}
+
+	var dirs []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(d.basePath, entry.Name())
+		if hasPlaylist(dir, 1) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func hasPlaylist(dir string, depth int) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".m3u8") {
+			return true
+		}
+	}
+	if depth == 0 {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && hasPlaylist(filepath.Join(dir, entry.Name()), depth-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// ffmpegOutputDirs maps the channel directory of every running HLS output
+// to the input ports of the processes writing it. Outputs below the base
+// path belong to the base path's direct child, so per-rendition directories
+// end up in one channel.
+func (d *Discovery) ffmpegOutputDirs() map[string][]int {
+	procs, err := d.scanner.Scan()
+	if err != nil {
+		log.Printf("discovery: process scan failed: %v", err)
+		return nil
+	}
+
+	dirs := make(map[string][]int)
+	for _, proc := range procs {
+		if !proc.IsFFmpeg() {
+			continue
+		}
+		job := ParseFFmpegArgs(proc.Args)
+		for _, output := range job.HLSOutputs() {
+			dir := d.channelDir(resolveProcPath(proc, output.HLS.PlaylistDir()))
+			if dir == d.basePath {
+				continue
+			}
+			dirs[dir] = mergePorts(dirs[dir], job.InputPorts())
+		}
+	}
+	return dirs
+}
+
+func (d *Discovery) channelDir(dir string) string {
+	rel, err := filepath.Rel(d.basePath, dir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return dir
+	}
+	first := strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]
+	return filepath.Join(d.basePath, first)
+}
+
+func mergePorts(ports, more []int) []int {
+	for _, port := range more {
+		known := false
+		for _, p := range ports {
+			known = known || p == port
+		}
+		if !known {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
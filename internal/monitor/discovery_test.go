@@ -0,0 +1,143 @@
+package monitor
+
+import (
+	"monitorMultiview/internal/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiscoveryFFmpegOutputs(t *testing.T) {
+	base := t.TempDir()
+	root := writeFakeProc(t,
+		fakeProcess{
+			pid:     100,
+			cmdline: "ffmpeg\x00-i\x00udp://:9001\x00-f\x00hls\x00" + filepath.Join(base, "news", "720p", "index.m3u8") + "\x00",
+			stat:    statLine(100, "ffmpeg", "S", 1, 0, 0, 4, 1000, 1),
+		},
+		fakeProcess{
+			// relative output resolved against the process cwd
+			pid:     200,
+			cmdline: "ffmpeg\x00-i\x00srt://:9002\x00-f\x00hls\x00sports/index.m3u8\x00",
+			stat:    statLine(200, "ffmpeg", "S", 1, 0, 0, 4, 1000, 1),
+			cwd:     base,
+		},
+	)
+
+	cfg := config.DefaultConfig()
+	cfg.HLS.BasePath = base
+	cfg.Channels.Count = 0
+	cfg.Channels.Discovery = DiscoverFFmpeg
+
+	d := NewDiscovery(cfg, NewProcScanner(root))
+	d.Discover()
+
+	var ids []string
+	for _, ch := range d.Channels() {
+		ids = append(ids, ch.ID)
+		if ch.Discovered != DiscoverFFmpeg || ch.Vanished {
+			t.Errorf("channel %s: discovered %q, vanished %v", ch.ID, ch.Discovered, ch.Vanished)
+		}
+		want := map[string]int{"news": 9001, "sports": 9002}[ch.ID]
+		if ch.Port != want {
+			t.Errorf("channel %s: port %d, want %d", ch.ID, ch.Port, want)
+		}
+		if ch.Path != filepath.Join(base, ch.ID) {
+			t.Errorf("channel %s: path %s", ch.ID, ch.Path)
+		}
+	}
+	if !reflect.DeepEqual(ids, []string{"news", "sports"}) {
+		t.Fatalf("discovered %q, want [news sports]", ids)
+	}
+}
+
+func TestDiscoveryPrunesVanishedChannels(t *testing.T) {
+	base := t.TempDir()
+	root := writeFakeProc(t, fakeProcess{
+		pid:     100,
+		cmdline: "ffmpeg\x00-i\x00udp://:9001\x00-f\x00hls\x00" + filepath.Join(base, "news", "index.m3u8") + "\x00",
+		stat:    statLine(100, "ffmpeg", "S", 1, 0, 0, 4, 1000, 1),
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.HLS.BasePath = base
+	cfg.Channels.Count = 0
+	cfg.Channels.Discovery = DiscoverFFmpeg
+
+	d := NewDiscovery(cfg, NewProcScanner(root))
+	d.Discover()
+	if channels := d.Channels(); len(channels) != 1 {
+		t.Fatalf("discovered %d channels, want 1", len(channels))
+	}
+
+	if err := os.RemoveAll(filepath.Join(root, "100")); err != nil {
+		t.Fatal(err)
+	}
+	d.Discover()
+	channels := d.Channels()
+	if len(channels) != 1 || !channels[0].Vanished {
+		t.Fatalf("after the process exited: %+v, want one vanished channel", channels)
+	}
+
+	// Still listed within the retention period
+	d.found[filepath.Join(base, "news")].VanishedAt = time.Now().Add(-VanishedRetention / 2)
+	d.Discover()
+	if len(d.Channels()) != 1 {
+		t.Fatal("vanished channel removed before the retention period")
+	}
+
+	d.found[filepath.Join(base, "news")].VanishedAt = time.Now().Add(-VanishedRetention)
+	d.Discover()
+	if channels := d.Channels(); len(channels) != 0 {
+		t.Fatalf("after the retention period: %+v, want none", channels)
+	}
+}
+
+func TestDiscoveryRelativeBasePath(t *testing.T) {
+	work := t.TempDir()
+	t.Chdir(work)
+	for _, dir := range []string{"output/news", "output/sports"} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "index.m3u8"), []byte("#EXTM3U\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := writeFakeProc(t,
+		fakeProcess{
+			pid:     100,
+			cmdline: "ffmpeg\x00-i\x00udp://:9001\x00-f\x00hls\x00" + filepath.Join(work, "output", "news", "index.m3u8") + "\x00",
+			stat:    statLine(100, "ffmpeg", "S", 1, 0, 0, 4, 1000, 1),
+		},
+		fakeProcess{
+			pid:     200,
+			cmdline: "ffmpeg\x00-i\x00udp://:9002\x00-f\x00hls\x00" + filepath.Join(work, "output", "sports", "index.m3u8") + "\x00",
+			stat:    statLine(200, "ffmpeg", "S", 1, 0, 0, 4, 1000, 1),
+		},
+	)
+
+	cfg := config.DefaultConfig()
+	cfg.HLS.BasePath = "./output"
+	cfg.Channels.Count = 0
+	cfg.Channels.Discovery = DiscoverBoth
+	cfg.Channels.List = []config.ChannelDefinition{{ID: "sports", Port: 9002, Path: "sports"}}
+
+	d := NewDiscovery(cfg, NewProcScanner(root))
+	d.Discover()
+
+	channels := d.Channels()
+	var ids []string
+	for _, ch := range channels {
+		ids = append(ids, ch.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"sports", "news"}) {
+		t.Fatalf("channels %q, want the configured sports and one discovered news", ids)
+	}
+	news := channels[1]
+	if news.Discovered != DiscoverBoth || news.Port != 9001 || news.Path != filepath.Join(work, "output", "news") {
+		t.Errorf("news = %+v, want found by both with port 9001", news)
+	}
+}
@@ -1,120 +1,404 @@
 package monitor
 
 import (
-	"fmt"
+	"log"
 	"monitorMultiview/internal/config"
-	"os/exec"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
 type FFmpegProcess struct {
-	ChannelID string
-	Port      int
-	PID       int
-	Status    string
-	Command   string
-	LastSeen  time.Time
+	ChannelID string     `json:"channel_id"`
+	Port      int        `json:"port"`
+	PID       int        `json:"pid"`
+	Role      string     `json:"role"`
+	Status    string     `json:"status"`
+	Command   string     `json:"command"`
+	Args      []string   `json:"args"`
+	Job       *FFmpegJob `json:"job"`
+	// Candidates lists every channel an ambiguous process matched
+	Candidates []string  `json:"candidates"`
+	LastSeen   time.Time `json:"last_seen"`
+
+	// Resource usage, refreshed on every scan
+	State      string    `json:"state"` // R, S, D, Z, T ... as reported by the kernel
+	CPUPercent float64   `json:"cpu_percent"`
+	RSS        int64     `json:"rss_bytes"`
+	Threads    int       `json:"threads"`
+	FDCount    int       `json:"fd_count"` // -1 when /proc/<pid>/fd is not readable
+	StartTime  time.Time `json:"start_time"`
+
+	startTicks uint64
+	outputKey  string
 }
 
+const (
+	StatusRunning   = "RUN"
+	StatusStopped   = "STOP"
+	StatusAmbiguous = "AMBIG"
+	StatusZombie    = "ZOMB"
+	StatusFlapping  = "FLAP"
+	// StatusGone marks a discovered channel that is no longer found
+	StatusGone = "GONE"
+)
+
+const (
+	RolePrimary   = "primary"
+	RoleBackup    = "backup"
+	RoleRendition = "rendition"
+)
+
+// FFmpegMonitor is safe for concurrent use. Processes it returns are
+// replaced, not modified, by later scans.
 type FFmpegMonitor struct {
-	processes map[string]*FFmpegProcess
+	mu         sync.Mutex
+	processes  map[string][]*FFmpegProcess
+	scanner    *ProcScanner
+	matcher    ChannelMatcher
+	cpuSamples map[int]cpuSample
+	tracker    *processTracker
+	cfg        *config.Config
+	// channels overrides the channels of cfg, e.g. with discovery
+	channels ChannelSource
 }
 
-func NewFFmpegMonitor() *FFmpegMonitor {
+// cpuSample is the cumulative CPU time of a process at a point in time.
+type cpuSample struct {
+	startTicks uint64
+	cpuTicks   uint64
+	at         time.Time
+}
+
+func NewFFmpegMonitor(cfg *config.Config) *FFmpegMonitor {
+	return NewFFmpegMonitorWithProcRoot(cfg, DefaultProcRoot)
+}
+
+// NewFFmpegMonitorWithProcRoot creates a monitor that reads processes from
+// an alternative procfs root, e.g. a fake tree in tests.
+func NewFFmpegMonitorWithProcRoot(cfg *config.Config, procRoot string) *FFmpegMonitor {
+	scanner := NewProcScanner(procRoot)
+
+	ffmpegConfig := cfg.FFmpeg
 	return &FFmpegMonitor{
-		processes: make(map[string]*FFmpegProcess),
+		processes:  make(map[string][]*FFmpegProcess),
+		scanner:    scanner,
+		matcher:    newMatcher(ffmpegConfig, scanner),
+		cpuSamples: make(map[int]cpuSample),
+		cfg:        cfg,
+		tracker: newProcessTracker(
+			ffmpegConfig.FlapThreshold,
+			time.Duration(ffmpegConfig.FlapWindow)*time.Second,
+		),
+	}
+}
+
+// Scanner returns the procfs scanner the monitor reads processes with.
+func (m *FFmpegMonitor) Scanner() *ProcScanner {
+	return m.scanner
+}
+
+func newMatcher(ffmpegConfig config.FFmpegConfig, scanner *ProcScanner) ChannelMatcher {
+	matcher, err := NewChannelMatcher(ffmpegConfig.MatchStrategy, ffmpegConfig.MatchRegex, ffmpegConfig.MatchLabel, scanner)
+	if err != nil {
+		log.Printf("falling back to port matching: %v", err)
+		return portMatcher{}
+	}
+	return matcher
+}
+
+// ApplyConfig switches to the channels, matching strategy and flap detection
+// settings of a reloaded configuration. Restart histories are kept.
+func (m *FFmpegMonitor) ApplyConfig(cfg *config.Config) {
+	matcher := newMatcher(cfg.FFmpeg, m.scanner)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg = cfg
+	m.matcher = matcher
+	m.tracker.flapThreshold = cfg.FFmpeg.FlapThreshold
+	m.tracker.flapWindow = time.Duration(cfg.FFmpeg.FlapWindow) * time.Second
+}
+
+// SetChannelSource replaces where the monitored channels come from; nil
+// restores the channels of the configuration.
+func (m *FFmpegMonitor) SetChannelSource(source ChannelSource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.channels = source
+}
+
+// channelList returns the monitored channels. m.mu must be held.
+func (m *FFmpegMonitor) channelList() []config.Channel {
+	if m.channels != nil {
+		return m.channels.Channels()
 	}
+	return m.cfg.ChannelList()
+}
+
+// SetMatcher replaces the strategy used to assign processes to channels.
+func (m *FFmpegMonitor) SetMatcher(matcher ChannelMatcher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.matcher = matcher
 }
 
 func (m *FFmpegMonitor) GetProcesses() []*FFmpegProcess {
 	m.updateProcesses()
-	
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	result := make([]*FFmpegProcess, 0, len(m.processes))
-	for _, proc := range m.processes {
-		result = append(result, proc)
+	for _, procs := range m.processes {
+		result = append(result, procs...)
 	}
 	return result
 }
 
+// Refresh rescans running processes.
+func (m *FFmpegMonitor) Refresh() {
+	m.updateProcesses()
+}
+
+// GetChannelProcesses returns every process serving channelID, primary first.
+// It uses the result of the last Refresh or GetProcesses call.
+func (m *FFmpegMonitor) GetChannelProcesses(channelID string) []*FFmpegProcess {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*FFmpegProcess(nil), m.processes[channelID]...)
+}
+
 func (m *FFmpegMonitor) updateProcesses() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	processes := m.scanFFmpegProcesses()
-	processMap := make(map[string]*FFmpegProcess)
-	
+	processMap := make(map[string][]*FFmpegProcess)
+
 	for _, proc := range processes {
-		processMap[proc.ChannelID] = proc
+		processMap[proc.ChannelID] = append(processMap[proc.ChannelID], proc)
+	}
+
+	for _, procs := range processMap {
+		assignRoles(procs)
+	}
+
+	now := time.Now()
+	for _, ch := range m.channelList() {
+		history := m.tracker.observe(ch.ID, processMap[ch.ID], now)
+		if history == nil || !history.Flapping {
+			continue
+		}
+		for _, proc := range processMap[ch.ID] {
+			if proc.Status == StatusRunning {
+				proc.Status = StatusFlapping
+			}
+		}
 	}
-	
+
 	m.processes = processMap
 }
 
+// GetChannelHistory returns a copy of the restart history of channelID, or
+// nil if no process has ever been seen for it.
+func (m *FFmpegMonitor) GetChannelHistory(channelID string) *ProcessHistory {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tracker.history(channelID)
+}
+
+// assignRoles labels the processes of one channel. Processes writing
+// distinct outputs are renditions of an ABR ladder; among processes writing
+// the same output the longest-running one is primary and the rest backups.
+func assignRoles(procs []*FFmpegProcess) {
+	sort.Slice(procs, func(i, j int) bool {
+		if procs[i].startTicks != procs[j].startTicks {
+			return procs[i].startTicks < procs[j].startTicks
+		}
+		return procs[i].PID < procs[j].PID
+	})
+
+	groups := make(map[string]bool)
+	for _, proc := range procs {
+		groups[proc.outputKey] = true
+	}
+
+	leaderRole := RolePrimary
+	if len(groups) > 1 {
+		leaderRole = RoleRendition
+	}
+
+	seen := make(map[string]bool)
+	for _, proc := range procs {
+		if seen[proc.outputKey] {
+			proc.Role = RoleBackup
+			continue
+		}
+		seen[proc.outputKey] = true
+		proc.Role = leaderRole
+	}
+
+	sort.SliceStable(procs, func(i, j int) bool {
+		return roleOrder(procs[i].Role) < roleOrder(procs[j].Role)
+	})
+}
+
+func roleOrder(role string) int {
+	switch role {
+	case RolePrimary:
+		return 0
+	case RoleRendition:
+		return 1
+	default:
+		return 2
+	}
+}
+
 func (m *FFmpegMonitor) scanFFmpegProcesses() []*FFmpegProcess {
-	cmd := exec.Command("ps", "aux")
-	output, err := cmd.Output()
+	procs, err := m.scanner.Scan()
 	if err != nil {
+		log.Printf("ffmpeg scan failed: %v", err)
 		return []*FFmpegProcess{}
 	}
-	
-	lines := strings.Split(string(output), "\n")
+
+	channels := m.channelList()
+	bootTime, err := m.scanner.BootTime()
+	if err != nil {
+		log.Printf("boot time unavailable: %v", err)
+	}
+
+	now := time.Now()
+	samples := make(map[int]cpuSample)
 	var processes []*FFmpegProcess
-	
-	for _, line := range lines {
-		if !strings.Contains(line, "ffmpeg") {
-			continue
-		}
-		
-		fields := strings.Fields(line)
-		if len(fields) < 11 {
+
+	for _, proc := range procs {
+		if !proc.IsFFmpeg() {
 			continue
 		}
-		
-		pid, err := strconv.Atoi(fields[1])
+
+		sample := cpuSample{startTicks: proc.StartTime, cpuTicks: proc.UTime + proc.STime, at: now}
+		samples[proc.PID] = sample
+		cpuPercent := m.cpuPercent(proc.PID, sample, bootTime)
+
+		fdCount, err := m.scanner.CountFDs(proc.PID)
 		if err != nil {
-			continue
+			fdCount = -1
 		}
-		
-		cmdLine := strings.Join(fields[10:], " ")
-		if len(cmdLine) > 50 {
-			cmdLine = cmdLine[:47] + "..."
+
+		var startTime time.Time
+		if !bootTime.IsZero() {
+			startTime = bootTime.Add(ticksToDuration(proc.StartTime))
 		}
-		
-		port := m.extractPortFromCommand(cmdLine)
-		channelID := m.getChannelIDFromPort(port)
-		
-		if channelID == "" {
+
+		job := ParseFFmpegArgs(proc.Args)
+		matches := m.matcher.Match(proc, job, channels)
+		if len(matches) == 0 {
 			continue
 		}
-		
-		processes = append(processes, &FFmpegProcess{
-			ChannelID: channelID,
-			Port:      port,
-			PID:       pid,
-			Status:    "RUN",
-			Command:   cmdLine,
-			LastSeen:  time.Now(),
-		})
-	}
-	
+
+		// An ambiguous process is listed under every candidate so it is
+		// visible, but flagged instead of silently picking one.
+		status := StatusRunning
+		if proc.State == "Z" {
+			status = StatusZombie
+		}
+		var candidates []string
+		if len(matches) > 1 {
+			status = StatusAmbiguous
+			for _, ch := range matches {
+				candidates = append(candidates, ch.ID)
+			}
+		}
+
+		for _, ch := range matches {
+			processes = append(processes, &FFmpegProcess{
+				ChannelID:  ch.ID,
+				Port:       inputPortFor(job, ch),
+				PID:        proc.PID,
+				Status:     status,
+				Command:    strings.Join(proc.Args, " "),
+				Args:       proc.Args,
+				Job:        job,
+				Candidates: candidates,
+				LastSeen:   now,
+				State:      proc.State,
+				CPUPercent: cpuPercent,
+				RSS:        proc.RSS,
+				Threads:    proc.NumThreads,
+				FDCount:    fdCount,
+				StartTime:  startTime,
+				startTicks: proc.StartTime,
+				outputKey:  outputKey(proc, job),
+			})
+		}
+	}
+
+	// Dropping samples of exited processes also protects against PID reuse
+	m.cpuSamples = samples
 	return processes
 }
 
-func (m *FFmpegMonitor) extractPortFromCommand(cmdLine string) int {
-	channels := config.GetChannels()
-	for _, ch := range channels {
-		if strings.Contains(cmdLine, fmt.Sprintf(":%d", ch.Port)) {
-			return ch.Port
+// cpuPercent computes CPU usage from the jiffies delta since the previous
+// scan. On the first sighting it falls back to the average since start.
+func (m *FFmpegMonitor) cpuPercent(pid int, sample cpuSample, bootTime time.Time) float64 {
+	if prev, ok := m.cpuSamples[pid]; ok && prev.startTicks == sample.startTicks {
+		elapsed := sample.at.Sub(prev.at).Seconds()
+		if elapsed > 0 && sample.cpuTicks >= prev.cpuTicks {
+			return float64(sample.cpuTicks-prev.cpuTicks) / ClockTicksPerSecond / elapsed * 100
 		}
+		return 0
 	}
-	return 0
+
+	if bootTime.IsZero() {
+		return 0
+	}
+	running := sample.at.Sub(bootTime.Add(ticksToDuration(sample.startTicks))).Seconds()
+	if running <= 0 {
+		return 0
+	}
+	return float64(sample.cpuTicks) / ClockTicksPerSecond / running * 100
+}
+
+// Uptime returns how long the process has been running.
+func (p *FFmpegProcess) Uptime() time.Duration {
+	if p.StartTime.IsZero() {
+		return 0
+	}
+	return time.Since(p.StartTime)
+}
+
+func ticksToDuration(ticks uint64) time.Duration {
+	return time.Duration(ticks) * time.Second / ClockTicksPerSecond
+}
+
+// outputKey identifies what a process writes, so that processes producing
+// the same playlist can be told apart from renditions.
+func outputKey(proc *ProcInfo, job *FFmpegJob) string {
+	var paths []string
+	for _, output := range job.HLSOutputs() {
+		paths = append(paths, resolveProcPath(proc, output.HLS.PlaylistPath))
+	}
+	if len(paths) == 0 {
+		for _, output := range job.Outputs {
+			paths = append(paths, output.URL)
+		}
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, "|")
 }
 
-func (m *FFmpegMonitor) getChannelIDFromPort(port int) string {
-	channels := config.GetChannels()
-	for _, ch := range channels {
-		if ch.Port == port {
-			return ch.ID
+// inputPortFor prefers a configured port of the channel when the job reads
+// from it, otherwise the first network input port.
+func inputPortFor(job *FFmpegJob, ch config.Channel) int {
+	ports := job.InputPorts()
+	for _, port := range ports {
+		if ch.HasPort(port) {
+			return port
 		}
 	}
-	return ""
-}
\ No newline at end of file
+	if len(ports) > 0 {
+		return ports[0]
+	}
+	return 0
+}
@@ -0,0 +1,235 @@
+package monitor
+
+import (
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FFmpegJob is the typed form of an ffmpeg command line.
+type FFmpegJob struct {
+	Inputs        []FFmpegInput     `json:"inputs"`
+	Outputs       []FFmpegOutput    `json:"outputs"`
+	GlobalOptions map[string]string `json:"global_options"`
+}
+
+type FFmpegInput struct {
+	URL      string            `json:"url"`
+	Protocol string            `json:"protocol"`
+	Host     string            `json:"host"`
+	Port     int               `json:"port"`
+	Format   string            `json:"format"`
+	Options  map[string]string `json:"options"`
+}
+
+type FFmpegOutput struct {
+	URL          string            `json:"url"`
+	Muxer        string            `json:"muxer"`
+	VideoCodec   string            `json:"video_codec"`
+	AudioCodec   string            `json:"audio_codec"`
+	VideoBitrate string            `json:"video_bitrate"`
+	AudioBitrate string            `json:"audio_bitrate"`
+	Preset       string            `json:"preset"`
+	HLS          *HLSMuxerOptions  `json:"hls"`
+	Options      map[string]string `json:"options"`
+}
+
+// HLSMuxerOptions holds the hls muxer settings of an output.
+type HLSMuxerOptions struct {
+	Time            float64  `json:"time"`
+	ListSize        int      `json:"list_size"`
+	Flags           []string `json:"flags"`
+	SegmentFilename string   `json:"segment_filename"`
+	SegmentType     string   `json:"segment_type"`
+	PlaylistType    string   `json:"playlist_type"`
+	MasterPlaylist  string   `json:"master_playlist"`
+	PlaylistPath    string   `json:"playlist_path"`
+}
+
+// ffmpeg options that do not consume a value. Each also has a "no" form,
+// such as -nostdin, and per-stream ones may carry a stream specifier.
+var ffmpegBoolOptions = map[string]bool{
+	// global
+	"y": true, "n": true, "stdin": true, "hide_banner": true,
+	"stats": true, "report": true, "benchmark": true, "benchmark_all": true,
+	"ignore_unknown": true, "copy_unknown": true, "recast_media": true,
+	"copyts": true, "start_at_zero": true, "xerror": true, "debug_ts": true,
+	"dump": true, "hex": true, "qphist": true, "vstats": true, "psnr": true,
+	"print_graphs": true,
+	// per input or output
+	"re": true, "accurate_seek": true, "autorotate": true, "autoscale": true,
+	"find_stream_info": true, "shortest": true, "bitexact": true,
+	"copyinkf": true, "fix_sub_duration": true, "fix_sub_duration_heartbeat": true,
+	"vn": true, "an": true, "sn": true, "dn": true,
+	// print and exit
+	"version": true, "buildconf": true, "formats": true, "muxers": true,
+	"demuxers": true, "devices": true, "codecs": true, "decoders": true,
+	"encoders": true, "bsfs": true, "protocols": true, "filters": true,
+	"pix_fmts": true, "layouts": true, "sample_fmts": true, "dispositions": true,
+	"colors": true, "hwaccels": true, "L": true,
+}
+
+// options that ffmpeg treats as global regardless of position
+var ffmpegGlobalOptions = map[string]bool{
+	"y": true, "n": true, "stdin": true, "hide_banner": true, "stats": true,
+	"stats_period": true, "report": true, "benchmark": true, "benchmark_all": true,
+	"loglevel": true, "v": true, "filter_complex": true, "filter_complex_script": true,
+	"progress": true, "xerror": true, "debug_ts": true, "ignore_unknown": true,
+	"copy_unknown": true, "recast_media": true, "copyts": true, "start_at_zero": true,
+	"dump": true, "hex": true, "qphist": true, "vstats": true, "vstats_file": true,
+	"psnr": true, "print_graphs": true, "init_hw_device": true, "filter_hw_device": true,
+	"sdp_file": true, "abort_on": true, "max_error_rate": true, "timelimit": true,
+}
+
+// ffmpegOptionName strips the stream specifier and the "no" prefix of a
+// boolean option, giving the name the tables above are keyed by.
+func ffmpegOptionName(name string) string {
+	name, _, _ = strings.Cut(name, ":")
+	if base, ok := strings.CutPrefix(name, "no"); ok && ffmpegBoolOptions[base] {
+		return base
+	}
+	return name
+}
+
+// ParseFFmpegArgs turns an ffmpeg argv (including argv[0]) into an FFmpegJob.
+// Options preceding -i belong to that input; options preceding any other
+// positional argument belong to that output.
+func ParseFFmpegArgs(args []string) *FFmpegJob {
+	job := &FFmpegJob{
+		GlobalOptions: make(map[string]string),
+	}
+	if len(args) == 0 {
+		return job
+	}
+
+	pending := make(map[string]string)
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+
+		if len(arg) < 2 || arg[0] != '-' {
+			job.Outputs = append(job.Outputs, newFFmpegOutput(arg, pending))
+			pending = make(map[string]string)
+			continue
+		}
+
+		name := arg[1:]
+		base := ffmpegOptionName(name)
+		value := ""
+		if !ffmpegBoolOptions[base] && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+
+		switch {
+		case name == "i":
+			job.Inputs = append(job.Inputs, newFFmpegInput(value, pending))
+			pending = make(map[string]string)
+		case ffmpegGlobalOptions[base]:
+			job.GlobalOptions[name] = value
+		default:
+			pending[name] = value
+		}
+	}
+
+	return job
+}
+
+func newFFmpegInput(rawURL string, options map[string]string) FFmpegInput {
+	input := FFmpegInput{
+		URL:      rawURL,
+		Protocol: "file",
+		Format:   options["f"],
+		Options:  options,
+	}
+
+	if u, err := url.Parse(rawURL); err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		input.Protocol = u.Scheme
+		input.Host = strings.TrimPrefix(u.Hostname(), "@")
+		input.Port, _ = strconv.Atoi(u.Port())
+	}
+
+	return input
+}
+
+func newFFmpegOutput(rawURL string, options map[string]string) FFmpegOutput {
+	output := FFmpegOutput{
+		URL:          rawURL,
+		Muxer:        options["f"],
+		VideoCodec:   firstOption(options, "c:v", "codec:v", "vcodec"),
+		AudioCodec:   firstOption(options, "c:a", "codec:a", "acodec"),
+		VideoBitrate: firstOption(options, "b:v", "b:v:0"),
+		AudioBitrate: firstOption(options, "b:a", "b:a:0"),
+		Preset:       firstOption(options, "preset", "preset:v"),
+		Options:      options,
+	}
+
+	if output.VideoCodec == "" || output.AudioCodec == "" {
+		if codec, ok := options["c"]; ok {
+			if output.VideoCodec == "" {
+				output.VideoCodec = codec
+			}
+			if output.AudioCodec == "" {
+				output.AudioCodec = codec
+			}
+		}
+	}
+
+	if output.Muxer == "" && strings.HasSuffix(rawURL, ".m3u8") {
+		output.Muxer = "hls"
+	}
+
+	if output.Muxer == "hls" {
+		hls := &HLSMuxerOptions{
+			SegmentFilename: options["hls_segment_filename"],
+			SegmentType:     options["hls_segment_type"],
+			PlaylistType:    options["hls_playlist_type"],
+			MasterPlaylist:  options["master_pl_name"],
+			PlaylistPath:    rawURL,
+		}
+		hls.Time, _ = strconv.ParseFloat(options["hls_time"], 64)
+		hls.ListSize, _ = strconv.Atoi(options["hls_list_size"])
+		if flags := options["hls_flags"]; flags != "" {
+			hls.Flags = strings.Split(flags, "+")
+		}
+		output.HLS = hls
+	}
+
+	return output
+}
+
+func firstOption(options map[string]string, names ...string) string {
+	for _, name := range names {
+		if value, ok := options[name]; ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// InputPorts returns the ports of all network inputs.
+func (j *FFmpegJob) InputPorts() []int {
+	var ports []int
+	for _, input := range j.Inputs {
+		if input.Port > 0 {
+			ports = append(ports, input.Port)
+		}
+	}
+	return ports
+}
+
+// HLSOutputs returns the outputs written with the hls muxer.
+func (j *FFmpegJob) HLSOutputs() []*FFmpegOutput {
+	var outputs []*FFmpegOutput
+	for i := range j.Outputs {
+		if j.Outputs[i].HLS != nil {
+			outputs = append(outputs, &j.Outputs[i])
+		}
+	}
+	return outputs
+}
+
+// PlaylistDir returns the directory the HLS playlist is written to.
+func (o *HLSMuxerOptions) PlaylistDir() string {
+	return filepath.Dir(o.PlaylistPath)
+}
@@ -0,0 +1,138 @@
+package monitor
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseFFmpegArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmdline string
+		args    []string // when an argument contains spaces
+		inputs  []string
+		outputs []string
+		globals []string
+		check   func(t *testing.T, job *FFmpegJob)
+	}{
+		{
+			name:    "multiview channel",
+			cmdline: "ffmpeg -f rtsp -i rtsp://192.168.1.100:8001/stream01 -c:v libx264 -preset fast -b:v 2000k -c:a aac -f hls -hls_time 6 -hls_list_size 10 -hls_flags delete_segments /output/channel01/index.m3u8",
+			inputs:  []string{"rtsp://192.168.1.100:8001/stream01"},
+			outputs: []string{"/output/channel01/index.m3u8"},
+			check: func(t *testing.T, job *FFmpegJob) {
+				in := job.Inputs[0]
+				if in.Protocol != "rtsp" || in.Host != "192.168.1.100" || in.Port != 8001 || in.Format != "rtsp" {
+					t.Errorf("input = %+v", in)
+				}
+				out := job.Outputs[0]
+				if out.Muxer != "hls" || out.VideoCodec != "libx264" || out.AudioCodec != "aac" ||
+					out.VideoBitrate != "2000k" || out.Preset != "fast" {
+					t.Errorf("output = %+v", out)
+				}
+				want := &HLSMuxerOptions{Time: 6, ListSize: 10, Flags: []string{"delete_segments"}, PlaylistPath: "/output/channel01/index.m3u8"}
+				if !reflect.DeepEqual(out.HLS, want) {
+					t.Errorf("hls = %+v, want %+v", out.HLS, want)
+				}
+			},
+		},
+		{
+			name:    "srt listener with boolean flags",
+			cmdline: "ffmpeg -hide_banner -nostdin -y -copyinkf -re -i srt://:9000?mode=listener -c copy -f hls -hls_time 2 /output/channel02/index.m3u8",
+			inputs:  []string{"srt://:9000?mode=listener"},
+			outputs: []string{"/output/channel02/index.m3u8"},
+			globals: []string{"hide_banner", "nostdin", "y"},
+			check: func(t *testing.T, job *FFmpegJob) {
+				if job.Inputs[0].Port != 9000 {
+					t.Errorf("input port = %d, want 9000", job.Inputs[0].Port)
+				}
+				if _, ok := job.Inputs[0].Options["copyinkf"]; !ok {
+					t.Errorf("input options = %v, want copyinkf", job.Inputs[0].Options)
+				}
+				if out := job.Outputs[0]; out.VideoCodec != "copy" || out.AudioCodec != "copy" {
+					t.Errorf("output codecs = %q/%q, want copy", out.VideoCodec, out.AudioCodec)
+				}
+			},
+		},
+		{
+			name:    "negated and stream-specific booleans",
+			cmdline: "ffmpeg -stdin -noaccurate_seek -start_at_zero -copyts -fix_sub_duration:s -i udp://239.0.0.1:8003 -dn -sn -nobitexact -f hls /output/channel03/index.m3u8",
+			inputs:  []string{"udp://239.0.0.1:8003"},
+			outputs: []string{"/output/channel03/index.m3u8"},
+			globals: []string{"copyts", "start_at_zero", "stdin"},
+		},
+		{
+			name: "adaptive ladder",
+			args: []string{"ffmpeg", "-i", "udp://@:8004", "-filter_complex", "[0:v]split=2[a][b]",
+				"-map", "[a]", "-map", "0:a", "-map", "[b]", "-map", "0:a",
+				"-c:v", "libx264", "-c:a", "aac", "-b:v:0", "4000k", "-b:v:1", "1500k",
+				"-f", "hls", "-hls_segment_type", "fmp4", "-master_pl_name", "master.m3u8",
+				"-var_stream_map", "v:0,a:0 v:1,a:1",
+				"-hls_segment_filename", "/output/channel04/%v/seg%05d.m4s", "/output/channel04/%v/index.m3u8"},
+			inputs:  []string{"udp://@:8004"},
+			outputs: []string{"/output/channel04/%v/index.m3u8"},
+			globals: []string{"filter_complex"},
+			check: func(t *testing.T, job *FFmpegJob) {
+				out := job.Outputs[0]
+				if out.VideoBitrate != "4000k" || out.HLS == nil || out.HLS.MasterPlaylist != "master.m3u8" || out.HLS.SegmentType != "fmp4" {
+					t.Errorf("output = %+v, hls = %+v", out, out.HLS)
+				}
+				if ports := job.InputPorts(); !reflect.DeepEqual(ports, []int{8004}) {
+					t.Errorf("InputPorts() = %v, want [8004]", ports)
+				}
+			},
+		},
+		{
+			name:    "two outputs",
+			cmdline: "ffmpeg -i udp://:8005 -c copy -f mpegts udp://127.0.0.1:9005 -c:v libx264 -an -f hls /output/channel05/index.m3u8",
+			inputs:  []string{"udp://:8005"},
+			outputs: []string{"udp://127.0.0.1:9005", "/output/channel05/index.m3u8"},
+			check: func(t *testing.T, job *FFmpegJob) {
+				if hls := job.HLSOutputs(); len(hls) != 1 || hls[0].URL != "/output/channel05/index.m3u8" {
+					t.Errorf("HLSOutputs() = %v", hls)
+				}
+			},
+		},
+		{
+			name:    "argv only",
+			cmdline: "ffmpeg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if args == nil {
+				args = strings.Fields(tt.cmdline)
+			}
+			job := ParseFFmpegArgs(args)
+
+			var inputs, outputs, globals []string
+			for _, in := range job.Inputs {
+				inputs = append(inputs, in.URL)
+			}
+			for _, out := range job.Outputs {
+				outputs = append(outputs, out.URL)
+			}
+			for name := range job.GlobalOptions {
+				globals = append(globals, name)
+			}
+			sort.Strings(globals)
+
+			if !reflect.DeepEqual(inputs, tt.inputs) {
+				t.Errorf("inputs = %q, want %q", inputs, tt.inputs)
+			}
+			if !reflect.DeepEqual(outputs, tt.outputs) {
+				t.Fatalf("outputs = %q, want %q", outputs, tt.outputs)
+			}
+			if !reflect.DeepEqual(globals, tt.globals) {
+				t.Errorf("global options = %q, want %q", globals, tt.globals)
+			}
+			if tt.check != nil {
+				tt.check(t, job)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	HealthOK      = "OK"
+	HealthWarn    = "WARN"
+	HealthStale   = "STALE"
+	HealthMissing = "MISSING"
+)
+
+// defaultTargetDuration is assumed when a playlist has no usable
+// #EXT-X-TARGETDURATION.
+const defaultTargetDuration = 10 * time.Second
+
+// HealthThresholds are multiples of the playlist target duration.
+type HealthThresholds struct {
+	WarnFactor  float64
+	StaleFactor float64
+}
+
+// HealthResult is the verdict for a single media playlist.
+type HealthResult struct {
+	Status string
+	Reason string
+}
+
+// healthRank orders statuses from best to worst.
+func healthRank(status string) int {
+	switch status {
+	case HealthOK:
+		return 0
+	case HealthWarn:
+		return 1
+	case HealthStale:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// WorseHealth returns the worse of two health statuses.
+func WorseHealth(a, b string) string {
+	if healthRank(b) > healthRank(a) {
+		return b
+	}
+	return a
+}
+
+// EvaluatePlaylistHealth compares the age of a media playlist and of the
+// newest segment against its target duration.
+func EvaluatePlaylistHealth(targetDuration int, playlistMod, segmentMod, now time.Time, thresholds HealthThresholds) HealthResult {
+	target := time.Duration(targetDuration) * time.Second
+	if target <= 0 {
+		target = defaultTargetDuration
+	}
+
+	if playlistMod.IsZero() {
+		return HealthResult{Status: HealthMissing, Reason: "playlist not found"}
+	}
+	if segmentMod.IsZero() {
+		return HealthResult{Status: HealthMissing, Reason: "no segments written"}
+	}
+
+	// The older of the two decides: a playlist that is rewritten without new
+	// segments is just as stalled as one that stops being rewritten.
+	age := now.Sub(playlistMod)
+	subject := "playlist"
+	if segAge := now.Sub(segmentMod); segAge > age {
+		age = segAge
+		subject = "newest segment"
+	}
+
+	stale := time.Duration(float64(target) * thresholds.StaleFactor)
+	warn := time.Duration(float64(target) * thresholds.WarnFactor)
+
+	switch {
+	case age > stale:
+		return HealthResult{Status: HealthStale, Reason: fmt.Sprintf("%s not updated for %s (limit %s)", subject, FormatDuration(age), FormatDuration(stale))}
+	case age > warn:
+		return HealthResult{Status: HealthWarn, Reason: fmt.Sprintf("%s not updated for %s", subject, FormatDuration(age))}
+	default:
+		return HealthResult{Status: HealthOK}
+	}
+}
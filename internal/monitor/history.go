@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"slices"
+	"time"
+)
+
+// maxRestartEvents bounds the restart log kept per channel.
+const maxRestartEvents = 50
+
+// RestartEvent records a new ffmpeg process replacing a previous one.
+type RestartEvent struct {
+	Time   time.Time `json:"time"`
+	OldPID int       `json:"old_pid"`
+	NewPID int       `json:"new_pid"`
+}
+
+// ProcessHistory is the per-channel process timeline kept across scans.
+type ProcessHistory struct {
+	ChannelID      string         `json:"channel_id"`
+	FirstSeen      time.Time      `json:"first_seen"`
+	LastSeen       time.Time      `json:"last_seen"`
+	CurrentPIDs    []int          `json:"current_pids"`
+	RestartCount   int            `json:"restart_count"`
+	Restarts       []RestartEvent `json:"restarts"`
+	Disappearances int            `json:"disappearances"`
+	LastDown       time.Time      `json:"last_down"`
+	Flapping       bool           `json:"flapping"`
+}
+
+// RestartsWithin counts restarts newer than now-window.
+func (h *ProcessHistory) RestartsWithin(window time.Duration, now time.Time) int {
+	count := 0
+	for _, event := range h.Restarts {
+		if now.Sub(event.Time) <= window {
+			count++
+		}
+	}
+	return count
+}
+
+// procIdentity tells a restarted process apart from a reused PID.
+type procIdentity struct {
+	pid        int
+	startTicks uint64
+}
+
+// processTracker detects PID changes and disappearances per channel.
+type processTracker struct {
+	flapThreshold int
+	flapWindow    time.Duration
+	histories     map[string]*ProcessHistory
+	current       map[string]map[procIdentity]bool
+	gone          map[string][]int // PIDs not yet replaced, oldest first
+}
+
+func newProcessTracker(flapThreshold int, flapWindow time.Duration) *processTracker {
+	return &processTracker{
+		flapThreshold: flapThreshold,
+		flapWindow:    flapWindow,
+		histories:     make(map[string]*ProcessHistory),
+		current:       make(map[string]map[procIdentity]bool),
+		gone:          make(map[string][]int),
+	}
+}
+
+// observe compares the processes found for channelID with the previous scan
+// and updates its history.
+func (t *processTracker) observe(channelID string, procs []*FFmpegProcess, now time.Time) *ProcessHistory {
+	history, known := t.histories[channelID]
+	if !known {
+		if len(procs) == 0 {
+			return nil
+		}
+		history = &ProcessHistory{ChannelID: channelID, FirstSeen: now}
+		t.histories[channelID] = history
+	}
+
+	previous := t.current[channelID]
+	current := make(map[procIdentity]bool, len(procs))
+	history.CurrentPIDs = history.CurrentPIDs[:0]
+	for _, proc := range procs {
+		id := procIdentity{pid: proc.PID, startTicks: proc.startTicks}
+		current[id] = true
+		history.CurrentPIDs = append(history.CurrentPIDs, proc.PID)
+	}
+
+	var vanished, appeared []int
+	for id := range previous {
+		if !current[id] {
+			vanished = append(vanished, id.pid)
+		}
+	}
+	for id := range current {
+		if !previous[id] {
+			appeared = append(appeared, id.pid)
+		}
+	}
+	slices.Sort(vanished)
+	slices.Sort(appeared)
+	if len(vanished) > 0 {
+		t.gone[channelID] = append(t.gone[channelID], vanished...)
+		history.Disappearances += len(vanished)
+		history.LastDown = now
+	}
+
+	// A new process is a restart when it takes the place of one that went
+	// away, or when the channel had none. The very first sighting is not,
+	// and neither is a process started next to the ones still running.
+	for _, pid := range appeared {
+		if !known {
+			break
+		}
+		gone := t.gone[channelID]
+		oldPID := 0
+		switch {
+		case len(gone) > 0:
+			oldPID, t.gone[channelID] = gone[0], gone[1:]
+		case len(previous) > 0:
+			continue
+		}
+		history.RestartCount++
+		history.Restarts = append(history.Restarts, RestartEvent{
+			Time:   now,
+			OldPID: oldPID,
+			NewPID: pid,
+		})
+		if len(history.Restarts) > maxRestartEvents {
+			history.Restarts = history.Restarts[len(history.Restarts)-maxRestartEvents:]
+		}
+	}
+
+	if len(procs) > 0 {
+		history.LastSeen = now
+	}
+	history.Flapping = t.flapThreshold > 0 && history.RestartsWithin(t.flapWindow, now) >= t.flapThreshold
+
+	t.current[channelID] = current
+	return history
+}
+
+func (t *processTracker) history(channelID string) *ProcessHistory {
+	history, ok := t.histories[channelID]
+	if !ok {
+		return nil
+	}
+	snapshot := *history
+	snapshot.CurrentPIDs = append([]int(nil), history.CurrentPIDs...)
+	snapshot.Restarts = append([]RestartEvent(nil), history.Restarts...)
+	return &snapshot
+}
@@ -0,0 +1,130 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func procs(pids ...int) []*FFmpegProcess {
+	var list []*FFmpegProcess
+	for _, pid := range pids {
+		list = append(list, &FFmpegProcess{PID: pid, startTicks: uint64(pid) * 100})
+	}
+	return list
+}
+
+func TestProcessTrackerRestarts(t *testing.T) {
+	type restart struct{ old, new int }
+	tests := []struct {
+		name           string
+		scans          [][]int
+		restarts       []restart
+		disappearances int
+	}{
+		{
+			name:  "first sighting",
+			scans: [][]int{{100, 101}},
+		},
+		{
+			name:     "replaced within one scan",
+			scans:    [][]int{{100}, {200}},
+			restarts: []restart{{100, 200}},
+			// the old process going away is still a disappearance
+			disappearances: 1,
+		},
+		{
+			name:           "down and back up",
+			scans:          [][]int{{100}, {}, {}, {200}},
+			restarts:       []restart{{100, 200}},
+			disappearances: 1,
+		},
+		{
+			name:  "additional process",
+			scans: [][]int{{100}, {100, 200}, {100, 200, 300}},
+		},
+		{
+			name:           "additional process after a restart",
+			scans:          [][]int{{100}, {200}, {200, 300}},
+			restarts:       []restart{{100, 200}},
+			disappearances: 1,
+		},
+		{
+			name:           "two replaced by one",
+			scans:          [][]int{{100, 101}, {200}},
+			restarts:       []restart{{100, 200}},
+			disappearances: 2,
+		},
+		{
+			name:           "the second replacement comes later",
+			scans:          [][]int{{100, 101}, {200}, {200, 201}},
+			restarts:       []restart{{100, 200}, {101, 201}},
+			disappearances: 2,
+		},
+		{
+			name:           "one replaced by two",
+			scans:          [][]int{{100}, {200, 201}},
+			restarts:       []restart{{100, 200}},
+			disappearances: 1,
+		},
+		{
+			name:           "one of two replaced",
+			scans:          [][]int{{100, 101}, {100, 200}},
+			restarts:       []restart{{101, 200}},
+			disappearances: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newProcessTracker(0, time.Minute)
+			now := time.Now()
+			var history *ProcessHistory
+			for i, pids := range tt.scans {
+				history = tracker.observe("ch01", procs(pids...), now.Add(time.Duration(i)*time.Second))
+			}
+
+			var restarts []restart
+			for _, event := range history.Restarts {
+				restarts = append(restarts, restart{event.OldPID, event.NewPID})
+			}
+			if !reflect.DeepEqual(restarts, tt.restarts) {
+				t.Errorf("restarts = %v, want %v", restarts, tt.restarts)
+			}
+			if history.RestartCount != len(tt.restarts) {
+				t.Errorf("restart count = %d, want %d", history.RestartCount, len(tt.restarts))
+			}
+			if history.Disappearances != tt.disappearances {
+				t.Errorf("disappearances = %d, want %d", history.Disappearances, tt.disappearances)
+			}
+		})
+	}
+}
+
+func TestProcessTrackerReusedPID(t *testing.T) {
+	tracker := newProcessTracker(0, time.Minute)
+	now := time.Now()
+	tracker.observe("ch01", []*FFmpegProcess{{PID: 100, startTicks: 1}}, now)
+	history := tracker.observe("ch01", []*FFmpegProcess{{PID: 100, startTicks: 2}}, now.Add(time.Second))
+	if history.RestartCount != 1 || history.Restarts[0].OldPID != 100 || history.Restarts[0].NewPID != 100 {
+		t.Errorf("restarts = %+v, want 100 replaced by a new process with the same PID", history.Restarts)
+	}
+}
+
+func TestProcessTrackerFlapping(t *testing.T) {
+	tracker := newProcessTracker(3, time.Minute)
+	now := time.Now()
+	var history *ProcessHistory
+	for i := range 4 {
+		history = tracker.observe("ch01", procs(100+i), now.Add(time.Duration(i)*time.Second))
+		if want := i >= 3; history.Flapping != want {
+			t.Errorf("after %d restarts: flapping %v, want %v", i, history.Flapping, want)
+		}
+	}
+
+	// Restarts age out of the window
+	history = tracker.observe("ch01", procs(103), now.Add(2*time.Minute))
+	if history.Flapping {
+		t.Error("still flapping after the window passed")
+	}
+}
@@ -1,37 +1,168 @@
 package monitor
 
 import (
+	"fmt"
+	"log"
 	"monitorMultiview/internal/config"
 	"os"
 	"path/filepath"
-	"sort"
-	"strings"
+	"sync"
 	"time"
 )
 
 type HLSPackage struct {
-	ChannelID    string
-	Path         string
-	M3U8Files    []string
-	LatestFile   string
-	LastUpdate   time.Time
-	TotalSize    int64
-	SegmentCount int
+	ChannelID    string    `json:"channel_id"`
+	Path         string    `json:"path"`
+	M3U8Files    []string  `json:"m3u8_files"`
+	LatestFile   string    `json:"latest_file"`
+	LastUpdate   time.Time `json:"last_update"`
+	TotalSize    int64     `json:"total_size"`
+	SegmentCount int       `json:"segment_count"`
+
+	// Health of the worst rendition in the package
+	Health          string          `json:"health"`
+	HealthReason    string          `json:"health_reason"`
+	TargetDuration  int             `json:"target_duration"`
+	PlaylistModTime time.Time       `json:"playlist_mod_time"`
+	LatestModTime   time.Time       `json:"latest_mod_time"`
+	Sequences       []SequenceState `json:"sequences"`
+	Findings        []Finding       `json:"findings"`
+
+	// MasterPlaylist is the master playlist relative to Path, if any
+	MasterPlaylist string       `json:"master_playlist"`
+	Renditions     []*Rendition `json:"renditions"`
+	// Playlists holds every playlist parsed during the scan by its path
+	// relative to Path
+	Playlists map[string]*M3U8Info `json:"playlists"`
 }
 
+// PlaylistAge returns how long ago the newest media playlist was written.
+func (p *HLSPackage) PlaylistAge() time.Duration {
+	if p.PlaylistModTime.IsZero() {
+		return 0
+	}
+	return time.Since(p.PlaylistModTime)
+}
+
+// MainPlaylist returns the playlist that best represents the package: the
+// master playlist, else the first rendition found, else the first playlist
+// file. It is empty when there is none.
+func (p *HLSPackage) MainPlaylist() string {
+	if p.MasterPlaylist != "" {
+		return p.MasterPlaylist
+	}
+	for _, rendition := range p.Renditions {
+		if rendition.Playlist != "" {
+			return rendition.Playlist
+		}
+	}
+	if len(p.M3U8Files) > 0 {
+		return p.M3U8Files[0]
+	}
+	return ""
+}
+
+// HLSMonitor is safe for concurrent use. Packages it returns are replaced,
+// not modified, by later scans.
 type HLSMonitor struct {
-	packages map[string]*HLSPackage
+	mu        sync.Mutex
+	packages  map[string]*HLSPackage
+	sequences *sequenceTracker
+
+	// indexes hold the file listing of each channel directory. While a
+	// watcher is running they are kept current from filesystem events and
+	// only rebuilt every rescanInterval as a safety net.
+	indexes        map[string]*packageIndex
+	watcher        dirWatcher
+	rescanInterval time.Duration
+	lastRescan     time.Time
+	cfg            *config.Config
+	// channels overrides the channels of cfg, e.g. with discovery
+	channels ChannelSource
+}
+
+func NewHLSMonitor(cfg *config.Config) *HLSMonitor {
+	hlsConfig := cfg.HLS
+	m := &HLSMonitor{
+		packages:       make(map[string]*HLSPackage),
+		sequences:      newSequenceTracker(),
+		indexes:        make(map[string]*packageIndex),
+		rescanInterval: time.Duration(hlsConfig.RescanInterval) * time.Second,
+		cfg:            cfg,
+	}
+
+	roots := []string{hlsConfig.BasePath}
+	for _, ch := range cfg.ChannelList() {
+		roots = append(roots, ch.Path)
+	}
+	watcher, err := newDirWatcher(hlsConfig.WatchMode, roots)
+	if err != nil {
+		log.Printf("Watching HLS directories failed, polling instead: %v", err)
+	}
+	if watcher != nil {
+		m.watcher = watcher
+		go m.handleEvents()
+	}
+	return m
 }
 
-func NewHLSMonitor() *HLSMonitor {
-	return &HLSMonitor{
-		packages: make(map[string]*HLSPackage),
+// SetChannelSource replaces where the monitored channels come from; nil
+// restores the channels of the configuration.
+func (m *HLSMonitor) SetChannelSource(source ChannelSource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.channels = source
+}
+
+// channelList returns the monitored channels. m.mu must be held.
+func (m *HLSMonitor) channelList() []config.Channel {
+	if m.channels != nil {
+		return m.channels.Channels()
 	}
+	return m.cfg.ChannelList()
+}
+
+// ApplyConfig switches to the channels and rescan interval of a reloaded
+// configuration. Channels are picked up on the next scan; the indexes and
+// sequence state of channels that keep their ID and directory are kept.
+func (m *HLSMonitor) ApplyConfig(cfg *config.Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg = cfg
+	m.rescanInterval = time.Duration(cfg.HLS.RescanInterval) * time.Second
+}
+
+// Close stops the directory watcher.
+func (m *HLSMonitor) Close() error {
+	if m.watcher == nil {
+		return nil
+	}
+	return m.watcher.Close()
 }
 
 func (m *HLSMonitor) GetPackages() []*HLSPackage {
 	m.updatePackages()
-	
+	return m.Packages()
+}
+
+func (m *HLSMonitor) GetPackageByChannel(channelID string) *HLSPackage {
+	m.updatePackages()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.packages[channelID]
+}
+
+func (m *HLSMonitor) updatePackages() {
+	m.ScanDirectories()
+	m.EvaluatePackages()
+}
+
+// Packages returns the packages of the last EvaluatePackages call without
+// touching the disk.
+func (m *HLSMonitor) Packages() []*HLSPackage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	result := make([]*HLSPackage, 0, len(m.packages))
 	for _, pkg := range m.packages {
 		result = append(result, pkg)
@@ -39,77 +170,263 @@ func (m *HLSMonitor) GetPackages() []*HLSPackage {
 	return result
 }
 
-func (m *HLSMonitor) GetPackageByChannel(channelID string) *HLSPackage {
-	m.updatePackages()
-	return m.packages[channelID]
+// ScanDirectories refreshes the file index of every channel directory. While
+// watching, directories are only walked every rescan interval and missing
+// ones are checked for having appeared.
+func (m *HLSMonitor) ScanDirectories() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	rescan := m.watcher == nil || now.Sub(m.lastRescan) >= m.rescanInterval
+	if rescan {
+		m.lastRescan = now
+	}
+
+	for _, ch := range m.channelList() {
+		idx := m.indexes[ch.ID]
+		switch {
+		case rescan || idx == nil || idx.root != filepath.Clean(ch.Path):
+			m.indexes[ch.ID] = m.indexChannel(ch.Path)
+		case !idx.exists:
+			// Nothing watches for the directory to appear; a stat per
+			// missing channel is cheap enough
+			if _, err := os.Stat(ch.Path); err == nil {
+				m.indexes[ch.ID] = m.indexChannel(ch.Path)
+			}
+		}
+	}
 }
 
-func (m *HLSMonitor) updatePackages() {
-	channels := config.GetChannels()
-	for _, ch := range channels {
-		pkg := m.scanHLSPackage(ch.ID, ch.Path)
-		if pkg != nil {
-			m.packages[ch.ID] = pkg
+// EvaluatePackages parses the playlists of every channel and rates their
+// health, using the file index for everything else.
+func (m *HLSMonitor) EvaluatePackages() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Rebuilt from scratch so channels that are gone drop out
+	packages := make(map[string]*HLSPackage)
+	indexes := make(map[string]*packageIndex)
+	for _, ch := range m.channelList() {
+		idx := m.indexes[ch.ID]
+		if idx == nil || idx.root != filepath.Clean(ch.Path) {
+			idx = m.indexChannel(ch.Path)
+		}
+		indexes[ch.ID] = idx
+
+		pkg := idx.toPackage(ch.ID)
+		if idx.exists {
+			m.evaluateHealth(pkg, idx, ch)
+		}
+		packages[ch.ID] = pkg
+	}
+	m.packages = packages
+	m.indexes = indexes
+}
+
+// indexChannel walks a channel directory and watches it and every
+// directory below it.
+func (m *HLSMonitor) indexChannel(root string) *packageIndex {
+	idx := buildIndex(root)
+	if idx.exists {
+		m.watchDirs(append([]string{idx.root}, idx.subdirs()...))
+	}
+	return idx
+}
+
+func (m *HLSMonitor) watchDirs(dirs []string) {
+	if m.watcher == nil {
+		return
+	}
+	for _, dir := range dirs {
+		if err := m.watcher.Add(dir); err != nil {
+			log.Printf("HLS watcher: %v", err)
+		}
+	}
+}
+
+// handleEvents applies filesystem events to the channel indexes until the
+// watcher is closed.
+func (m *HLSMonitor) handleEvents() {
+	for event := range m.watcher.Events() {
+		m.mu.Lock()
+		if event.Overflow {
+			// Events were dropped, rebuild everything on the next update
+			m.lastRescan = time.Time{}
+		} else {
+			m.applyEvent(event.Path)
 		}
+		m.mu.Unlock()
 	}
 }
 
-func (m *HLSMonitor) scanHLSPackage(channelID, path string) *HLSPackage {
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return &HLSPackage{
-			ChannelID:  channelID,
-			Path:       path,
-			M3U8Files:  []string{},
-			LatestFile: "N/A",
-			LastUpdate: time.Now(),
+func (m *HLSMonitor) applyEvent(path string) {
+	for _, idx := range m.indexes {
+		if !idx.contains(path) {
+			continue
 		}
+		idx.update(path)
+		if entry, ok := idx.lookup(path); ok && entry.isDir {
+			// Files may have been created before the new directory was
+			// watched
+			m.watchDirs(idx.addTree(path))
+		}
+	}
+}
+
+// evaluateHealth validates every playlist of the package, walks the master
+// playlist to its renditions and rates each media playlist. The package
+// takes the health of its worst rendition. Master playlists are static and
+// therefore not rated themselves.
+func (m *HLSMonitor) evaluateHealth(pkg *HLSPackage, idx *packageIndex, ch config.Channel) {
+	thresholds := HealthThresholds{
+		WarnFactor:  ch.WarnFactor,
+		StaleFactor: ch.StaleFactor,
 	}
-	
-	var m3u8Files []string
-	var latestFile string
-	var latestTime time.Time
-	var totalSize int64
-	segmentCount := 0
-	
-	err := filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+	now := time.Now()
+
+	playlists := make(map[string]*parsedPlaylist)
+	pkg.Playlists = make(map[string]*M3U8Info)
+	var masters []*parsedPlaylist
+	for _, name := range pkg.M3U8Files {
+		playlistPath := filepath.Join(pkg.Path, name)
+		// Playlists are rewritten in place; the index may be a scan behind
+		entry, ok := idx.refresh(playlistPath)
+		if !ok {
+			continue
+		}
+		info, err := ParseM3U8(playlistPath)
 		if err != nil {
-			return nil
-		}
-		
-		if strings.HasSuffix(info.Name(), ".m3u8") {
-			m3u8Files = append(m3u8Files, info.Name())
-		}
-		
-		if strings.HasSuffix(info.Name(), ".ts") || strings.HasSuffix(info.Name(), ".m4s") {
-			segmentCount++
-			if info.ModTime().After(latestTime) {
-				latestTime = info.ModTime()
-				latestFile = info.Name()
+			continue
+		}
+
+		pl := &parsedPlaylist{name: name, path: playlistPath, info: info, modTime: entry.modTime}
+		playlists[name] = pl
+		pkg.Playlists[name] = info
+
+		previous := m.sequences.get(playlistPath)
+		pkg.Findings = append(pkg.Findings, validatePlaylist(name, info, filepath.Dir(playlistPath), previous, idx.has)...)
+		if info.IsMaster {
+			masters = append(masters, pl)
+		}
+	}
+
+	referenced := make(map[string]bool)
+	master := pickMaster(masters, ch.MasterPlaylist)
+	if master != nil {
+		pkg.MasterPlaylist = master.name
+		for _, ref := range masterReferences(master.info) {
+			rendition := &Rendition{
+				URI:        ref.uri,
+				Type:       ref.kind,
+				Bandwidth:  ref.bandwidth,
+				Resolution: ref.resolution,
+				Codecs:     ref.codecs,
+				Name:       ref.name,
 			}
+
+			name, ok := resolvePlaylistRef(master.name, ref.uri)
+			switch {
+			case !ok:
+				rendition.Health = HealthWarn
+				rendition.HealthReason = "external URI is not checked"
+			case referenced[name]:
+				continue
+			case playlists[name] == nil:
+				rendition.Playlist = name
+				rendition.Health = HealthMissing
+				rendition.HealthReason = "playlist not found"
+			default:
+				rendition.Playlist = name
+				m.evaluateRendition(rendition, playlists[name], pkg, idx, now, thresholds)
+			}
+			referenced[name] = true
+			pkg.Renditions = append(pkg.Renditions, rendition)
 		}
-		
-		totalSize += info.Size()
-		return nil
-	})
-	
-	if err != nil {
-		return nil
 	}
-	
-	if latestFile == "" {
-		latestFile = "N/A"
+
+	// Media playlists outside the master's tree are rated on their own
+	for _, name := range pkg.M3U8Files {
+		pl := playlists[name]
+		if pl == nil || pl.info.IsMaster || referenced[name] {
+			continue
+		}
+		rendition := &Rendition{URI: name, Playlist: name, Type: RenditionStandalone}
+		m.evaluateRendition(rendition, pl, pkg, idx, now, thresholds)
+		pkg.Renditions = append(pkg.Renditions, rendition)
 	}
-	
-	sort.Strings(m3u8Files)
-	
-	return &HLSPackage{
-		ChannelID:    channelID,
-		Path:         path,
-		M3U8Files:    m3u8Files,
-		LatestFile:   latestFile,
-		LastUpdate:   time.Now(),
-		TotalSize:    totalSize,
-		SegmentCount: segmentCount,
+
+	// Renditions the channel is configured to have but does not
+	for _, expected := range ch.Renditions {
+		if !hasRendition(pkg.Renditions, expected) {
+			pkg.Renditions = append(pkg.Renditions, &Rendition{
+				URI:          expected,
+				Playlist:     filepath.Clean(expected),
+				Type:         RenditionExpected,
+				Health:       HealthMissing,
+				HealthReason: "expected rendition not found",
+			})
+		}
+	}
+
+	pkg.Health = HealthMissing
+	pkg.HealthReason = "no media playlist found"
+	for i, rendition := range pkg.Renditions {
+		if i == 0 || healthRank(rendition.Health) > healthRank(pkg.Health) {
+			pkg.Health = rendition.Health
+			pkg.HealthReason = rendition.HealthReason
+			if rendition.Health != HealthOK {
+				pkg.HealthReason = fmt.Sprintf("%s: %s", rendition.URI, rendition.HealthReason)
+			}
+			pkg.TargetDuration = rendition.TargetDuration
+		}
+		if rendition.LastUpdate.After(pkg.PlaylistModTime) {
+			pkg.PlaylistModTime = rendition.LastUpdate
+		}
+	}
+
+	if ch.MasterPlaylist != "" && master == nil {
+		pkg.Health = HealthMissing
+		pkg.HealthReason = fmt.Sprintf("master playlist %s not found", ch.MasterPlaylist)
 	}
 }
 
+// evaluateRendition rates a single media playlist.
+func (m *HLSMonitor) evaluateRendition(rendition *Rendition, pl *parsedPlaylist, pkg *HLSPackage, idx *packageIndex, now time.Time, thresholds HealthThresholds) {
+	info := pl.info
+	rendition.SegmentCount = len(info.Segments)
+	rendition.TargetDuration = info.TargetDuration
+	rendition.LastUpdate = pl.modTime
+	rendition.Ended = info.EndList
+
+	// Prefer the mtime of the last segment this playlist references
+	segmentMod := pkg.LatestModTime
+	if n := len(info.Segments); n > 0 {
+		rendition.LatestSegment = info.Segments[n-1].URI
+		segPath := filepath.Join(filepath.Dir(pl.path), info.Segments[n-1].URI)
+		if entry, ok := idx.refresh(segPath); ok {
+			segmentMod = entry.modTime
+		}
+	}
+
+	// A playlist with #EXT-X-ENDLIST is finished and no longer expected
+	// to be updated
+	if info.EndList {
+		rendition.Health = HealthOK
+		rendition.HealthReason = "ended (#EXT-X-ENDLIST)"
+		return
+	}
+
+	result := EvaluatePlaylistHealth(info.TargetDuration, pl.modTime, segmentMod, now, thresholds)
+
+	sequence := m.sequences.observe(pl.path, info.MediaSequence, len(info.Segments), info.TargetDuration, thresholds.StaleFactor, now)
+	sequence.Playlist = pl.name
+	rendition.Sequence = &sequence
+	pkg.Sequences = append(pkg.Sequences, sequence)
+	if seqHealth := sequenceHealth(sequence.Status); healthRank(seqHealth) > healthRank(result.Status) {
+		result = HealthResult{Status: seqHealth, Reason: "media sequence " + sequence.Detail}
+	}
+
+	rendition.Health = result.Status
+	rendition.HealthReason = result.Reason
+}
@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"fmt"
+	"monitorMultiview/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeMediaPlaylist writes a live playlist listing segments first..last
+// and the segments themselves.
+func writeMediaPlaylist(t *testing.T, dir string, first, last int) {
+	t.Helper()
+	var b strings.Builder
+	fmt.Fprintf(&b, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:%d\n", first)
+	for n := first; n <= last; n++ {
+		fmt.Fprintf(&b, "#EXTINF:6.0,\nseg%d.ts\n", n)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("seg%d.ts", n)), []byte("ts"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.m3u8"), []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// evaluated returns the package of the last EvaluatePackages call, unlike
+// GetPackageByChannel, which scans again first.
+func evaluated(m *HLSMonitor, channelID string) *HLSPackage {
+	for _, pkg := range m.Packages() {
+		if pkg.ChannelID == channelID {
+			return pkg
+		}
+	}
+	return nil
+}
+
+func TestEvaluatePackagesAheadOfScan(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "channel01")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeMediaPlaylist(t, dir, 1, 1)
+
+	cfg := config.DefaultConfig()
+	cfg.HLS.BasePath = base
+	cfg.HLS.WatchMode = "poll"
+	cfg.Channels.Count = 1
+	m := NewHLSMonitor(cfg)
+	defer m.Close()
+
+	m.ScanDirectories()
+	m.EvaluatePackages()
+	before := evaluated(m, "ch01")
+	if before == nil || before.Health != HealthOK {
+		t.Fatalf("first evaluation: %+v, want an OK package", before)
+	}
+
+	// ffmpeg writes a segment and the playlist before the next directory
+	// scan; the playlist is evaluated first
+	modTime := time.Now().Add(time.Second)
+	writeMediaPlaylist(t, dir, 1, 2)
+	if err := os.Chtimes(filepath.Join(dir, "index.m3u8"), modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	m.EvaluatePackages()
+
+	pkg := evaluated(m, "ch01")
+	for _, finding := range pkg.Findings {
+		if finding.RuleID == RuleMissingFile {
+			t.Errorf("unexpected finding %s", finding)
+		}
+	}
+	if !pkg.PlaylistModTime.Equal(modTime) {
+		t.Errorf("playlist modified %s, want %s from disk", pkg.PlaylistModTime, modTime)
+	}
+
+	// A segment that really is missing is reported once the index knows
+	if err := os.Remove(filepath.Join(dir, "seg2.ts")); err != nil {
+		t.Fatal(err)
+	}
+	m.ScanDirectories()
+	m.EvaluatePackages()
+	var missing int
+	for _, finding := range evaluated(m, "ch01").Findings {
+		if finding.RuleID == RuleMissingFile {
+			missing++
+		}
+	}
+	if missing != 1 {
+		t.Errorf("%d missing file findings after removing seg2.ts, want 1", missing)
+	}
+}
@@ -0,0 +1,217 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// indexedFile is what we remember about a file inside a channel directory.
+type indexedFile struct {
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+// packageIndex is the file listing of one channel directory, keyed by path
+// relative to root. It is either rebuilt by walking the directory or kept
+// up to date from filesystem events.
+type packageIndex struct {
+	root   string
+	exists bool
+	files  map[string]indexedFile
+}
+
+// buildIndex walks root and records every file below it.
+func buildIndex(root string) *packageIndex {
+	idx := &packageIndex{
+		root:  filepath.Clean(root),
+		files: make(map[string]indexedFile),
+	}
+	if _, err := os.Stat(idx.root); err != nil {
+		return idx
+	}
+	idx.exists = true
+	idx.addTree(idx.root)
+	return idx
+}
+
+// addTree records dir and everything below it, returning the directories
+// found so they can be watched.
+func (idx *packageIndex) addTree(dir string) []string {
+	var dirs []string
+	filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			dirs = append(dirs, filePath)
+		}
+		if filePath == idx.root {
+			return nil
+		}
+		if rel, err := filepath.Rel(idx.root, filePath); err == nil {
+			idx.files[rel] = indexedFile{size: info.Size(), modTime: info.ModTime(), isDir: info.IsDir()}
+		}
+		return nil
+	})
+	return dirs
+}
+
+// contains reports whether filePath is root or lies below it.
+func (idx *packageIndex) contains(filePath string) bool {
+	return filePath == idx.root || strings.HasPrefix(filePath, idx.root+string(filepath.Separator))
+}
+
+// lookup returns the entry for an absolute path inside the index.
+func (idx *packageIndex) lookup(filePath string) (indexedFile, bool) {
+	if !idx.contains(filePath) || filePath == idx.root {
+		return indexedFile{}, false
+	}
+	rel, err := filepath.Rel(idx.root, filePath)
+	if err != nil {
+		return indexedFile{}, false
+	}
+	entry, ok := idx.files[rel]
+	return entry, ok
+}
+
+// has reports whether a file exists. Files the index does not list are
+// checked on disk, as a playlist may already reference segments written
+// after the last scan.
+func (idx *packageIndex) has(filePath string) bool {
+	filePath = filepath.Clean(filePath)
+	if _, ok := idx.lookup(filePath); ok {
+		return true
+	}
+	if !idx.contains(filePath) {
+		_, err := os.Stat(filePath)
+		return err == nil
+	}
+	_, ok := idx.refresh(filePath)
+	return ok
+}
+
+// refresh re-reads a single file from disk and returns its entry, for files
+// whose current state matters more than the cost of a stat.
+func (idx *packageIndex) refresh(filePath string) (indexedFile, bool) {
+	filePath = filepath.Clean(filePath)
+	idx.update(filePath)
+	return idx.lookup(filePath)
+}
+
+// update refreshes a single path from disk, dropping it if it is gone.
+func (idx *packageIndex) update(filePath string) {
+	if filePath == idx.root {
+		if _, err := os.Stat(idx.root); err != nil {
+			idx.exists = false
+			idx.files = make(map[string]indexedFile)
+		} else {
+			idx.exists = true
+		}
+		return
+	}
+
+	if !idx.contains(filePath) {
+		return
+	}
+	rel, err := filepath.Rel(idx.root, filePath)
+	if err != nil {
+		return
+	}
+
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		idx.remove(rel)
+		return
+	}
+	idx.exists = true
+	idx.files[rel] = indexedFile{size: info.Size(), modTime: info.ModTime(), isDir: info.IsDir()}
+}
+
+// remove drops rel and, if it was a directory, everything below it.
+func (idx *packageIndex) remove(rel string) {
+	entry, ok := idx.files[rel]
+	delete(idx.files, rel)
+	if ok && !entry.isDir {
+		return
+	}
+	prefix := rel + string(filepath.Separator)
+	for name := range idx.files {
+		if strings.HasPrefix(name, prefix) {
+			delete(idx.files, name)
+		}
+	}
+}
+
+// subdirs returns the absolute paths of all directories below root.
+func (idx *packageIndex) subdirs() []string {
+	var dirs []string
+	for name, entry := range idx.files {
+		if entry.isDir {
+			dirs = append(dirs, filepath.Join(idx.root, name))
+		}
+	}
+	return dirs
+}
+
+// toPackage summarizes the index. Health is filled in by the caller.
+func (idx *packageIndex) toPackage(channelID string) *HLSPackage {
+	if !idx.exists {
+		return &HLSPackage{
+			ChannelID:    channelID,
+			Path:         idx.root,
+			M3U8Files:    []string{},
+			LatestFile:   "N/A",
+			LastUpdate:   time.Now(),
+			Health:       HealthMissing,
+			HealthReason: "directory does not exist",
+		}
+	}
+
+	m3u8Files := []string{}
+	var latestFile string
+	var latestTime time.Time
+	var totalSize int64
+	segmentCount := 0
+
+	for name, entry := range idx.files {
+		if entry.isDir {
+			continue
+		}
+		base := filepath.Base(name)
+
+		if strings.HasSuffix(base, ".m3u8") {
+			m3u8Files = append(m3u8Files, name)
+		}
+
+		if strings.HasSuffix(base, ".ts") || strings.HasSuffix(base, ".m4s") {
+			segmentCount++
+			if entry.modTime.After(latestTime) {
+				latestTime = entry.modTime
+				latestFile = base
+			}
+		}
+
+		totalSize += entry.size
+	}
+
+	if latestFile == "" {
+		latestFile = "N/A"
+	}
+
+	sort.Strings(m3u8Files)
+
+	return &HLSPackage{
+		ChannelID:     channelID,
+		Path:          idx.root,
+		M3U8Files:     m3u8Files,
+		LatestFile:    latestFile,
+		LastUpdate:    time.Now(),
+		TotalSize:     totalSize,
+		SegmentCount:  segmentCount,
+		LatestModTime: latestTime,
+	}
+}
@@ -0,0 +1,43 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPackageIndexAheadOfScan(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("index.m3u8")
+	writeFile("seg1.ts")
+	idx := buildIndex(dir)
+
+	// ffmpeg writes a segment and rewrites the playlist before the index
+	// sees either
+	writeFile("seg2.ts")
+	modTime := time.Now().Add(time.Second)
+	playlist := filepath.Join(dir, "index.m3u8")
+	if err := os.Chtimes(playlist, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	if !idx.has(filepath.Join(dir, "seg2.ts")) {
+		t.Error("has(seg2.ts) = false for a file written after the scan")
+	}
+	if idx.has(filepath.Join(dir, "seg3.ts")) {
+		t.Error("has(seg3.ts) = true for a file that does not exist")
+	}
+	if idx.has(filepath.Join(t.TempDir(), "seg1.ts")) {
+		t.Error("has() = true for a missing file outside the root")
+	}
+	if entry, ok := idx.refresh(playlist); !ok || !entry.modTime.Equal(modTime) {
+		t.Errorf("refresh(index.m3u8) = %v, %v, want the modification time %s from disk", entry.modTime, ok, modTime)
+	}
+}
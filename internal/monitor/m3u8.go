@@ -2,25 +2,122 @@ package monitor
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// M3U8Info is a parsed HLS playlist (RFC 8216). Master playlists fill
+// Variants and Media; media playlists fill Segments.
 type M3U8Info struct {
-	Version        int
-	TargetDuration int
-	MediaSequence  int
-	Segments       []SegmentInfo
-	Content        string
+	Version               int           `json:"version"`
+	IsMaster              bool          `json:"is_master"`
+	StartsWithEXTM3U      bool          `json:"starts_with_extm3u"`
+	TargetDuration        int           `json:"target_duration"`
+	MediaSequence         int           `json:"media_sequence"`
+	DiscontinuitySequence int           `json:"discontinuity_sequence"`
+	PlaylistType          string        `json:"playlist_type"`
+	EndList               bool          `json:"end_list"`
+	IFramesOnly           bool          `json:"iframes_only"`
+	IndependentSegments   bool          `json:"independent_segments"`
+	Segments              []SegmentInfo `json:"segments"`
+	Variants              []VariantInfo `json:"variants"`
+	Media                 []MediaInfo   `json:"media"`
+	Content               string        `json:"content"`
+	// Errors lists malformed tags; parsing continues past them
+	Errors []*ParseError `json:"errors"`
 }
 
 type SegmentInfo struct {
-	Duration float64
-	URI      string
+	Duration        float64    `json:"duration"`
+	Title           string     `json:"title"`
+	URI             string     `json:"uri"`
+	Line            int        `json:"line"`
+	Sequence        int        `json:"sequence"`
+	Discontinuity   bool       `json:"discontinuity"`
+	ProgramDateTime time.Time  `json:"program_date_time"`
+	ByteRange       *ByteRange `json:"byte_range"`
+	Key             *KeyInfo   `json:"key"`
+	Map             *MapInfo   `json:"map"`
 }
 
+// VariantInfo is an #EXT-X-STREAM-INF (or I-FRAME-STREAM-INF) entry.
+type VariantInfo struct {
+	URI              string  `json:"uri"`
+	Line             int     `json:"line"`
+	Bandwidth        int     `json:"bandwidth"`
+	AverageBandwidth int     `json:"average_bandwidth"`
+	Resolution       string  `json:"resolution"`
+	Width            int     `json:"width"`
+	Height           int     `json:"height"`
+	Codecs           string  `json:"codecs"`
+	FrameRate        float64 `json:"frame_rate"`
+	Audio            string  `json:"audio"`
+	Video            string  `json:"video"`
+	Subtitles        string  `json:"subtitles"`
+	IFrameOnly       bool    `json:"iframe_only"`
+}
+
+// MediaInfo is an #EXT-X-MEDIA rendition.
+type MediaInfo struct {
+	Type       string `json:"type"`
+	GroupID    string `json:"group_id"`
+	Name       string `json:"name"`
+	Language   string `json:"language"`
+	URI        string `json:"uri"`
+	Default    bool   `json:"default"`
+	AutoSelect bool   `json:"auto_select"`
+	Line       int    `json:"line"`
+}
+
+type KeyInfo struct {
+	Method            string `json:"method"`
+	URI               string `json:"uri"`
+	IV                string `json:"iv"`
+	KeyFormat         string `json:"key_format"`
+	KeyFormatVersions string `json:"key_format_versions"`
+}
+
+type MapInfo struct {
+	URI       string     `json:"uri"`
+	ByteRange *ByteRange `json:"byte_range"`
+}
+
+type ByteRange struct {
+	Length    int64 `json:"length"`
+	Offset    int64 `json:"offset"`
+	HasOffset bool  `json:"has_offset"`
+}
+
+// ParseError reports a malformed tag and the line it was found on.
+type ParseError struct {
+	Line int
+	Tag  string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s: %v", e.Line, e.Tag, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ParseError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Line  int    `json:"line"`
+		Tag   string `json:"tag"`
+		Error string `json:"error"`
+	}{e.Line, e.Tag, e.Err.Error()})
+}
+
+// ParseM3U8 reads and parses a playlist file. The returned error is only set
+// when the file cannot be read; malformed tags are collected in Errors.
 func ParseM3U8(filePath string) (*M3U8Info, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -28,46 +125,399 @@ func ParseM3U8(filePath string) (*M3U8Info, error) {
 	}
 	defer file.Close()
 
-	info := &M3U8Info{
-		Segments: make([]SegmentInfo, 0),
+	return ParseM3U8Reader(file)
+}
+
+// ParseM3U8Reader parses a playlist from r.
+func ParseM3U8Reader(r io.Reader) (*M3U8Info, error) {
+	p := &playlistParser{
+		info: &M3U8Info{
+			Segments: make([]SegmentInfo, 0),
+		},
 	}
 
 	var content strings.Builder
-	scanner := bufio.NewScanner(file)
-	var currentDuration float64
-
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
+		p.line++
 		line := strings.TrimSpace(scanner.Text())
 		content.WriteString(line + "\n")
+		p.parseLine(line)
+	}
+
+	p.finish()
+	p.info.Content = content.String()
+	return p.info, scanner.Err()
+}
+
+type playlistParser struct {
+	info *M3U8Info
+	line int
+
+	// state carried from tags to the next URI line
+	pendingSegment *SegmentInfo
+	pendingVariant *VariantInfo
+	key            *KeyInfo
+	initMap        *MapInfo
+}
+
+func (p *playlistParser) errorf(tag string, format string, args ...interface{}) {
+	p.info.Errors = append(p.info.Errors, &ParseError{
+		Line: p.line,
+		Tag:  tag,
+		Err:  fmt.Errorf(format, args...),
+	})
+}
+
+func (p *playlistParser) segment() *SegmentInfo {
+	if p.pendingSegment == nil {
+		p.pendingSegment = &SegmentInfo{}
+	}
+	return p.pendingSegment
+}
+
+func (p *playlistParser) parseLine(line string) {
+	if p.line == 1 {
+		if line == "#EXTM3U" {
+			p.info.StartsWithEXTM3U = true
+			return
+		}
+		p.errorf("#EXTM3U", "playlist must start with #EXTM3U")
+	}
+
+	if line == "" {
+		return
+	}
+
+	if !strings.HasPrefix(line, "#") {
+		p.parseURI(line)
+		return
+	}
+	if !strings.HasPrefix(line, "#EXT") {
+		return // comment
+	}
+
+	tag, value, _ := strings.Cut(line, ":")
+	switch tag {
+	case "#EXTM3U":
+		p.errorf(tag, "#EXTM3U must only appear on the first line")
+	case "#EXT-X-VERSION":
+		p.info.Version = p.parseInt(tag, value)
+	case "#EXT-X-TARGETDURATION":
+		p.info.TargetDuration = p.parseInt(tag, value)
+	case "#EXT-X-MEDIA-SEQUENCE":
+		p.info.MediaSequence = p.parseInt(tag, value)
+	case "#EXT-X-DISCONTINUITY-SEQUENCE":
+		p.info.DiscontinuitySequence = p.parseInt(tag, value)
+	case "#EXT-X-PLAYLIST-TYPE":
+		if value != "VOD" && value != "EVENT" {
+			p.errorf(tag, "unknown playlist type %q", value)
+		}
+		p.info.PlaylistType = value
+	case "#EXT-X-ENDLIST":
+		p.info.EndList = true
+	case "#EXT-X-I-FRAMES-ONLY":
+		p.info.IFramesOnly = true
+	case "#EXT-X-INDEPENDENT-SEGMENTS":
+		p.info.IndependentSegments = true
+	case "#EXTINF":
+		p.parseEXTINF(tag, value)
+	case "#EXT-X-BYTERANGE":
+		if br, err := parseByteRange(value); err != nil {
+			p.errorf(tag, "%v", err)
+		} else {
+			p.segment().ByteRange = br
+		}
+	case "#EXT-X-DISCONTINUITY":
+		p.segment().Discontinuity = true
+	case "#EXT-X-PROGRAM-DATE-TIME":
+		if t, err := parseProgramDateTime(value); err != nil {
+			p.errorf(tag, "%v", err)
+		} else {
+			p.segment().ProgramDateTime = t
+		}
+	case "#EXT-X-KEY":
+		p.parseKey(tag, value)
+	case "#EXT-X-MAP":
+		p.parseMap(tag, value)
+	case "#EXT-X-STREAM-INF":
+		p.info.IsMaster = true
+		if p.pendingVariant != nil {
+			p.errorf(tag, "#EXT-X-STREAM-INF on line %d is not followed by a URI", p.pendingVariant.Line)
+			p.pendingVariant = nil
+		}
+		if variant := p.parseVariant(tag, value); variant != nil {
+			p.pendingVariant = variant
+		}
+	case "#EXT-X-I-FRAME-STREAM-INF":
+		p.info.IsMaster = true
+		if variant := p.parseVariant(tag, value); variant != nil {
+			variant.IFrameOnly = true
+			if variant.URI == "" {
+				p.errorf(tag, "missing URI attribute")
+			}
+			p.info.Variants = append(p.info.Variants, *variant)
+		}
+	case "#EXT-X-MEDIA":
+		p.info.IsMaster = true
+		p.parseMedia(tag, value)
+	}
+	// Unknown tags are ignored as required by RFC 8216 section 4.1
+}
+
+func (p *playlistParser) parseURI(uri string) {
+	if p.pendingVariant != nil {
+		p.pendingVariant.URI = uri
+		p.pendingVariant.Line = p.line
+		p.info.Variants = append(p.info.Variants, *p.pendingVariant)
+		p.pendingVariant = nil
+		return
+	}
+
+	if p.pendingSegment == nil || p.pendingSegment.Line == 0 {
+		p.errorf("URI", "%s is not preceded by #EXTINF or #EXT-X-STREAM-INF", uri)
+		p.segment().Line = p.line
+	}
+
+	segment := p.pendingSegment
+	segment.URI = uri
+	segment.Sequence = p.info.MediaSequence + len(p.info.Segments)
+	segment.Key = p.key
+	segment.Map = p.initMap
+	p.info.Segments = append(p.info.Segments, *segment)
+	p.pendingSegment = nil
+}
+
+func (p *playlistParser) parseEXTINF(tag, value string) {
+	durationStr, title, _ := strings.Cut(value, ",")
+	duration, err := strconv.ParseFloat(strings.TrimSpace(durationStr), 64)
+	if err != nil || duration < 0 {
+		p.errorf(tag, "invalid duration %q", durationStr)
+	}
+
+	segment := p.segment()
+	segment.Duration = duration
+	segment.Title = title
+	segment.Line = p.line
+}
+
+func (p *playlistParser) parseKey(tag, value string) {
+	attrs, err := parseAttributes(value)
+	if err != nil {
+		p.errorf(tag, "%v", err)
+		return
+	}
+
+	key := &KeyInfo{
+		Method:            attrs["METHOD"],
+		URI:               attrs["URI"],
+		IV:                attrs["IV"],
+		KeyFormat:         attrs["KEYFORMAT"],
+		KeyFormatVersions: attrs["KEYFORMATVERSIONS"],
+	}
+	switch key.Method {
+	case "NONE":
+		p.key = nil
+		return
+	case "AES-128", "SAMPLE-AES", "SAMPLE-AES-CTR":
+		if key.URI == "" {
+			p.errorf(tag, "METHOD=%s requires a URI", key.Method)
+		}
+	case "":
+		p.errorf(tag, "missing METHOD attribute")
+	default:
+		p.errorf(tag, "unknown METHOD %q", key.Method)
+	}
+	p.key = key
+}
+
+func (p *playlistParser) parseMap(tag, value string) {
+	attrs, err := parseAttributes(value)
+	if err != nil {
+		p.errorf(tag, "%v", err)
+		return
+	}
+
+	m := &MapInfo{URI: attrs["URI"]}
+	if m.URI == "" {
+		p.errorf(tag, "missing URI attribute")
+	}
+	if raw, ok := attrs["BYTERANGE"]; ok {
+		br, err := parseByteRange(raw)
+		if err != nil {
+			p.errorf(tag, "%v", err)
+		}
+		m.ByteRange = br
+	}
+	p.initMap = m
+}
+
+func (p *playlistParser) parseVariant(tag, value string) *VariantInfo {
+	attrs, err := parseAttributes(value)
+	if err != nil {
+		p.errorf(tag, "%v", err)
+		return nil
+	}
+
+	variant := &VariantInfo{
+		URI:        attrs["URI"],
+		Line:       p.line,
+		Resolution: attrs["RESOLUTION"],
+		Codecs:     attrs["CODECS"],
+		Audio:      attrs["AUDIO"],
+		Video:      attrs["VIDEO"],
+		Subtitles:  attrs["SUBTITLES"],
+	}
+
+	bandwidth, ok := attrs["BANDWIDTH"]
+	if !ok {
+		p.errorf(tag, "missing BANDWIDTH attribute")
+	} else if variant.Bandwidth, err = strconv.Atoi(bandwidth); err != nil {
+		p.errorf(tag, "invalid BANDWIDTH %q", bandwidth)
+	}
+	if raw, ok := attrs["AVERAGE-BANDWIDTH"]; ok {
+		if variant.AverageBandwidth, err = strconv.Atoi(raw); err != nil {
+			p.errorf(tag, "invalid AVERAGE-BANDWIDTH %q", raw)
+		}
+	}
+	if variant.Resolution != "" {
+		w, h, ok := strings.Cut(variant.Resolution, "x")
+		variant.Width, err = strconv.Atoi(w)
+		if err == nil && ok {
+			variant.Height, err = strconv.Atoi(h)
+		}
+		if err != nil || !ok {
+			p.errorf(tag, "invalid RESOLUTION %q", variant.Resolution)
+		}
+	}
+	if raw, ok := attrs["FRAME-RATE"]; ok {
+		if variant.FrameRate, err = strconv.ParseFloat(raw, 64); err != nil {
+			p.errorf(tag, "invalid FRAME-RATE %q", raw)
+		}
+	}
+	return variant
+}
+
+func (p *playlistParser) parseMedia(tag, value string) {
+	attrs, err := parseAttributes(value)
+	if err != nil {
+		p.errorf(tag, "%v", err)
+		return
+	}
+
+	media := MediaInfo{
+		Type:       attrs["TYPE"],
+		GroupID:    attrs["GROUP-ID"],
+		Name:       attrs["NAME"],
+		Language:   attrs["LANGUAGE"],
+		URI:        attrs["URI"],
+		Default:    attrs["DEFAULT"] == "YES",
+		AutoSelect: attrs["AUTOSELECT"] == "YES",
+		Line:       p.line,
+	}
+	switch media.Type {
+	case "AUDIO", "VIDEO", "SUBTITLES", "CLOSED-CAPTIONS":
+	default:
+		p.errorf(tag, "invalid TYPE %q", media.Type)
+	}
+	if media.GroupID == "" {
+		p.errorf(tag, "missing GROUP-ID attribute")
+	}
+	if media.Name == "" {
+		p.errorf(tag, "missing NAME attribute")
+	}
+	p.info.Media = append(p.info.Media, media)
+}
+
+func (p *playlistParser) parseInt(tag, value string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < 0 {
+		p.errorf(tag, "invalid integer %q", value)
+		return 0
+	}
+	return n
+}
+
+func (p *playlistParser) finish() {
+	if p.line == 0 {
+		p.errorf("#EXTM3U", "empty playlist")
+	}
+	if p.pendingVariant != nil {
+		p.errorf("#EXT-X-STREAM-INF", "not followed by a URI")
+	}
+	if p.pendingSegment != nil && p.pendingSegment.Line > 0 {
+		p.errorf("#EXTINF", "not followed by a URI")
+	}
+	if !p.info.IsMaster && p.info.TargetDuration == 0 && len(p.info.Segments) > 0 {
+		p.errorf("#EXT-X-TARGETDURATION", "required tag is missing")
+	}
+}
+
+// parseAttributes splits an attribute list (RFC 8216 section 4.2). Quoted
+// strings are returned without their quotes.
+func parseAttributes(s string) (map[string]string, error) {
+	attrs := make(map[string]string)
+	for len(s) > 0 {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return attrs, fmt.Errorf("malformed attribute list near %q", s)
+		}
+		name := strings.TrimSpace(s[:eq])
+		s = s[eq+1:]
 
-		if strings.HasPrefix(line, "#EXT-X-VERSION:") {
-			version, _ := strconv.Atoi(strings.TrimPrefix(line, "#EXT-X-VERSION:"))
-			info.Version = version
-		} else if strings.HasPrefix(line, "#EXT-X-TARGETDURATION:") {
-			duration, _ := strconv.Atoi(strings.TrimPrefix(line, "#EXT-X-TARGETDURATION:"))
-			info.TargetDuration = duration
-		} else if strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:") {
-			sequence, _ := strconv.Atoi(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"))
-			info.MediaSequence = sequence
-		} else if strings.HasPrefix(line, "#EXTINF:") {
-			durationStr := strings.TrimPrefix(line, "#EXTINF:")
-			durationStr = strings.TrimSuffix(durationStr, ",")
-			if idx := strings.Index(durationStr, ","); idx > 0 {
-				durationStr = durationStr[:idx]
+		var value string
+		if strings.HasPrefix(s, "\"") {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				return attrs, fmt.Errorf("unterminated quoted string for %s", name)
 			}
-			duration, _ := strconv.ParseFloat(durationStr, 64)
-			currentDuration = duration
-		} else if !strings.HasPrefix(line, "#") && line != "" {
-			info.Segments = append(info.Segments, SegmentInfo{
-				Duration: currentDuration,
-				URI:      line,
-			})
-			currentDuration = 0
+			value = s[1 : end+1]
+			s = s[end+2:]
+		} else if comma := strings.IndexByte(s, ','); comma >= 0 {
+			value = s[:comma]
+			s = s[comma:]
+		} else {
+			value = s
+			s = ""
+		}
+
+		if _, dup := attrs[name]; dup {
+			return attrs, fmt.Errorf("duplicate attribute %s", name)
+		}
+		attrs[name] = value
+		s = strings.TrimPrefix(s, ",")
+	}
+	return attrs, nil
+}
+
+func parseByteRange(value string) (*ByteRange, error) {
+	lengthStr, offsetStr, hasOffset := strings.Cut(value, "@")
+	length, err := strconv.ParseInt(lengthStr, 10, 64)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid byte range %q", value)
+	}
+
+	br := &ByteRange{Length: length, HasOffset: hasOffset}
+	if hasOffset {
+		if br.Offset, err = strconv.ParseInt(offsetStr, 10, 64); err != nil || br.Offset < 0 {
+			return nil, fmt.Errorf("invalid byte range offset %q", value)
 		}
 	}
+	return br, nil
+}
 
-	info.Content = content.String()
-	return info, scanner.Err()
+var programDateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+}
+
+func parseProgramDateTime(value string) (time.Time, error) {
+	for _, layout := range programDateTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date-time %q", value)
 }
 
 func FormatFileSize(bytes int64) string {
@@ -81,4 +531,18 @@ func FormatFileSize(bytes int64) string {
 		exp++
 	}
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
-}
\ No newline at end of file
+}
+
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd%02dh", int(d.Hours())/24, int(d.Hours())%24)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+}
@@ -0,0 +1,241 @@
+package monitor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, playlist string) *M3U8Info {
+	t.Helper()
+	info, err := ParseM3U8Reader(strings.NewReader(playlist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestParseMediaPlaylist(t *testing.T) {
+	info := parse(t, `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:120
+#EXT-X-DISCONTINUITY-SEQUENCE:2
+# a comment
+#EXTINF:6.006,first
+seg120.ts
+#EXT-X-DISCONTINUITY
+#EXT-X-PROGRAM-DATE-TIME:2024-05-01T12:00:00.000Z
+#EXTINF:5.5,
+seg121.ts
+#EXT-X-ENDLIST
+`)
+	if len(info.Errors) != 0 {
+		t.Fatalf("errors: %v", info.Errors)
+	}
+	if info.IsMaster || !info.StartsWithEXTM3U || info.Version != 3 || info.TargetDuration != 6 ||
+		info.MediaSequence != 120 || info.DiscontinuitySequence != 2 || !info.EndList {
+		t.Errorf("info = %+v", info)
+	}
+	if len(info.Segments) != 2 {
+		t.Fatalf("%d segments, want 2", len(info.Segments))
+	}
+	first, second := info.Segments[0], info.Segments[1]
+	if first.URI != "seg120.ts" || first.Duration != 6.006 || first.Title != "first" || first.Sequence != 120 || first.Line != 7 {
+		t.Errorf("first segment = %+v", first)
+	}
+	if second.URI != "seg121.ts" || second.Sequence != 121 || !second.Discontinuity || second.ProgramDateTime.IsZero() {
+		t.Errorf("second segment = %+v", second)
+	}
+	if first.Discontinuity || !first.ProgramDateTime.IsZero() {
+		t.Errorf("segment tags leaked back to the first segment: %+v", first)
+	}
+}
+
+func TestParseMasterPlaylist(t *testing.T) {
+	info := parse(t, `#EXTM3U
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud",NAME="English",LANGUAGE="en",DEFAULT=YES,AUTOSELECT=YES,URI="audio/en.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=4000000,AVERAGE-BANDWIDTH=3500000,RESOLUTION=1920x1080,FRAME-RATE=29.970,CODECS="avc1.640028,mp4a.40.2",AUDIO="aud"
+1080p/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=1500000,RESOLUTION=1280x720,CODECS="avc1.4d401f,mp4a.40.2",AUDIO="aud"
+720p/index.m3u8
+#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=200000,URI="1080p/iframes.m3u8"
+`)
+	if len(info.Errors) != 0 {
+		t.Fatalf("errors: %v", info.Errors)
+	}
+	if !info.IsMaster || !info.IndependentSegments || len(info.Segments) != 0 {
+		t.Errorf("info = %+v", info)
+	}
+
+	var uris []string
+	for _, v := range info.Variants {
+		uris = append(uris, v.URI)
+	}
+	if want := []string{"1080p/index.m3u8", "720p/index.m3u8", "1080p/iframes.m3u8"}; !reflect.DeepEqual(uris, want) {
+		t.Fatalf("variants = %q, want %q", uris, want)
+	}
+	v := info.Variants[0]
+	if v.Bandwidth != 4000000 || v.AverageBandwidth != 3500000 || v.Width != 1920 || v.Height != 1080 ||
+		v.FrameRate != 29.97 || v.Audio != "aud" || v.Line != 5 {
+		t.Errorf("first variant = %+v", v)
+	}
+	if !info.Variants[2].IFrameOnly {
+		t.Error("I-frame variant not marked")
+	}
+
+	if len(info.Media) != 1 {
+		t.Fatalf("%d media, want 1", len(info.Media))
+	}
+	if m := info.Media[0]; m.Type != "AUDIO" || m.GroupID != "aud" || m.Name != "English" || m.URI != "audio/en.m3u8" ||
+		!m.Default || !m.AutoSelect || m.Line != 3 {
+		t.Errorf("media = %+v", m)
+	}
+}
+
+func TestParseQuotedCommas(t *testing.T) {
+	info := parse(t, `#EXTM3U
+#EXT-X-STREAM-INF:CODECS="avc1.64001f,mp4a.40.2",BANDWIDTH=800000,RESOLUTION=640x360
+360p.m3u8
+`)
+	if len(info.Errors) != 0 {
+		t.Fatalf("errors: %v", info.Errors)
+	}
+	if v := info.Variants[0]; v.Codecs != "avc1.64001f,mp4a.40.2" || v.Bandwidth != 800000 || v.Resolution != "640x360" {
+		t.Errorf("variant = %+v", v)
+	}
+
+	attrs, err := parseAttributes(`METHOD=AES-128,URI="https://keys.example/k?a=1,b=2",IV=0x00000000000000000000000000000001`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"METHOD": "AES-128",
+		"URI":    "https://keys.example/k?a=1,b=2",
+		"IV":     "0x00000000000000000000000000000001",
+	}
+	if !reflect.DeepEqual(attrs, want) {
+		t.Errorf("attributes = %v, want %v", attrs, want)
+	}
+}
+
+func TestParseKeyAndMapCarriedAcrossSegments(t *testing.T) {
+	info := parse(t, `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:4
+#EXT-X-MAP:URI="init.mp4",BYTERANGE="720@0"
+#EXT-X-KEY:METHOD=AES-128,URI="key1.bin"
+#EXTINF:4.0,
+seg1.m4s
+#EXTINF:4.0,
+seg2.m4s
+#EXT-X-KEY:METHOD=NONE
+#EXTINF:4.0,
+seg3.m4s
+#EXT-X-MAP:URI="init2.mp4"
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="key2.bin",KEYFORMAT="identity"
+#EXTINF:4.0,
+seg4.m4s
+`)
+	if len(info.Errors) != 0 {
+		t.Fatalf("errors: %v", info.Errors)
+	}
+	tests := []struct {
+		key     string
+		initMap string
+	}{
+		{"key1.bin", "init.mp4"},
+		{"key1.bin", "init.mp4"},
+		{"", "init.mp4"},
+		{"key2.bin", "init2.mp4"},
+	}
+	for i, tt := range tests {
+		seg := info.Segments[i]
+		var key string
+		if seg.Key != nil {
+			key = seg.Key.URI
+		}
+		if key != tt.key {
+			t.Errorf("segment %s: key %q, want %q", seg.URI, key, tt.key)
+		}
+		if seg.Map == nil || seg.Map.URI != tt.initMap {
+			t.Errorf("segment %s: map %+v, want %s", seg.URI, seg.Map, tt.initMap)
+		}
+	}
+	if br := info.Segments[0].Map.ByteRange; br == nil || br.Length != 720 || !br.HasOffset || br.Offset != 0 {
+		t.Errorf("map byte range = %+v", br)
+	}
+	if k := info.Segments[3].Key; k.Method != "SAMPLE-AES" || k.KeyFormat != "identity" {
+		t.Errorf("key = %+v", k)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		playlist string
+		line     int
+		tag      string
+	}{
+		{
+			name:     "malformed EXTINF",
+			playlist: "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.0,\nseg1.ts\n#EXTINF:six,\nseg2.ts\n",
+			line:     5,
+			tag:      "#EXTINF",
+		},
+		{
+			name:     "negative EXTINF",
+			playlist: "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:-1,\nseg1.ts\n",
+			line:     3,
+			tag:      "#EXTINF",
+		},
+		{
+			name:     "STREAM-INF without a URI at the end",
+			playlist: "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000\n",
+			line:     2,
+			tag:      "#EXT-X-STREAM-INF",
+		},
+		{
+			name:     "STREAM-INF followed by another",
+			playlist: "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000\n#EXT-X-STREAM-INF:BANDWIDTH=400000\nlow.m3u8\n",
+			line:     3,
+			tag:      "#EXT-X-STREAM-INF",
+		},
+		{
+			name:     "I-FRAME-STREAM-INF without a URI",
+			playlist: "#EXTM3U\n#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=200000\n",
+			line:     2,
+			tag:      "#EXT-X-I-FRAME-STREAM-INF",
+		},
+		{
+			name:     "URI without EXTINF",
+			playlist: "#EXTM3U\n#EXT-X-TARGETDURATION:6\nseg1.ts\n",
+			line:     3,
+			tag:      "URI",
+		},
+		{
+			name:     "missing EXTM3U",
+			playlist: "#EXT-X-TARGETDURATION:6\n",
+			line:     1,
+			tag:      "#EXTM3U",
+		},
+		{
+			name:     "unterminated quote",
+			playlist: "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXT-X-KEY:METHOD=AES-128,URI=\"key.bin\n",
+			line:     3,
+			tag:      "#EXT-X-KEY",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := parse(t, tt.playlist)
+			if len(info.Errors) != 1 {
+				t.Fatalf("errors = %v, want one", info.Errors)
+			}
+			if err := info.Errors[0]; err.Line != tt.line || err.Tag != tt.tag {
+				t.Errorf("error %q at line %d for %s, want line %d for %s", err, err.Line, err.Tag, tt.line, tt.tag)
+			}
+		})
+	}
+}
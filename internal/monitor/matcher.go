@@ -0,0 +1,182 @@
+package monitor
+
+import (
+	"fmt"
+	"monitorMultiview/internal/config"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	MatchByPort       = "port"
+	MatchByOutputPath = "output_path"
+	MatchByRegex      = "regex"
+	MatchByLabel      = "label"
+)
+
+// ChannelMatcher decides which channels an ffmpeg process serves. More than
+// one returned channel means the match is ambiguous.
+type ChannelMatcher interface {
+	Match(proc *ProcInfo, job *FFmpegJob, channels []config.Channel) []config.Channel
+}
+
+// NewChannelMatcher builds the matcher for a configured strategy.
+func NewChannelMatcher(strategy, pattern, label string, scanner *ProcScanner) (ChannelMatcher, error) {
+	switch strategy {
+	case "", MatchByPort:
+		return portMatcher{}, nil
+	case MatchByOutputPath:
+		return outputPathMatcher{}, nil
+	case MatchByRegex:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match regex: %w", err)
+		}
+		if re.NumSubexp() == 0 {
+			return nil, fmt.Errorf("match regex must contain a capture group: %s", pattern)
+		}
+		return regexMatcher{re: re}, nil
+	case MatchByLabel:
+		if label == "" {
+			return nil, fmt.Errorf("label match strategy requires a label name")
+		}
+		return labelMatcher{key: label, scanner: scanner}, nil
+	default:
+		return nil, fmt.Errorf("unknown match strategy: %q", strategy)
+	}
+}
+
+// portMatcher matches when an input URL uses exactly one of the channel's
+// ports.
+type portMatcher struct{}
+
+func (portMatcher) Match(proc *ProcInfo, job *FFmpegJob, channels []config.Channel) []config.Channel {
+	var matches []config.Channel
+	ports := job.InputPorts()
+	for _, ch := range channels {
+		for _, port := range ports {
+			if ch.HasPort(port) {
+				matches = append(matches, ch)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// outputPathMatcher matches when an HLS playlist or segment template is
+// written below the channel's directory.
+type outputPathMatcher struct{}
+
+func (outputPathMatcher) Match(proc *ProcInfo, job *FFmpegJob, channels []config.Channel) []config.Channel {
+	var paths []string
+	for _, output := range job.HLSOutputs() {
+		paths = append(paths, resolveProcPath(proc, output.HLS.PlaylistPath))
+		if output.HLS.SegmentFilename != "" {
+			paths = append(paths, resolveProcPath(proc, output.HLS.SegmentFilename))
+		}
+	}
+
+	var matches []config.Channel
+	for _, ch := range channels {
+		for _, path := range paths {
+			if isUnderDir(path, ch.Path) {
+				matches = append(matches, ch)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// regexMatcher runs a regex over the joined argv and maps the first capture
+// group (or the group named "channel") onto a channel.
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+func (r regexMatcher) Match(proc *ProcInfo, job *FFmpegJob, channels []config.Channel) []config.Channel {
+	groups := r.re.FindStringSubmatch(strings.Join(proc.Args, " "))
+	if groups == nil {
+		return nil
+	}
+
+	value := groups[1]
+	if idx := r.re.SubexpIndex("channel"); idx > 0 {
+		value = groups[idx]
+	}
+	return channelsForValue(value, channels)
+}
+
+// labelMatcher reads the channel from an environment variable of the
+// process, falling back to `-metadata <key>=<value>` on the command line.
+type labelMatcher struct {
+	key     string
+	scanner *ProcScanner
+}
+
+func (l labelMatcher) Match(proc *ProcInfo, job *FFmpegJob, channels []config.Channel) []config.Channel {
+	if l.scanner != nil {
+		if env, err := l.scanner.ReadEnviron(proc.PID); err == nil {
+			if value, ok := env[l.key]; ok {
+				return channelsForValue(value, channels)
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(proc.Args); i++ {
+		if !strings.HasPrefix(proc.Args[i], "-metadata") {
+			continue
+		}
+		if key, value, ok := strings.Cut(proc.Args[i+1], "="); ok && key == l.key {
+			return channelsForValue(value, channels)
+		}
+	}
+	return nil
+}
+
+// channelsForValue resolves a label to channels by ID, directory name or
+// channel number.
+func channelsForValue(value string, channels []config.Channel) []config.Channel {
+	number, numErr := strconv.Atoi(value)
+
+	var matches []config.Channel
+	for _, ch := range channels {
+		if ch.ID == value || filepath.Base(ch.Path) == value || (numErr == nil && ch.Number > 0 && ch.Number == number) {
+			matches = append(matches, ch)
+		}
+	}
+	return matches
+}
+
+func resolveProcPath(proc *ProcInfo, path string) string {
+	if !filepath.IsAbs(path) && proc.Cwd != "" {
+		path = filepath.Join(proc.Cwd, path)
+	}
+	return filepath.Clean(path)
+}
+
+// absPath makes a configured path absolute, so it compares with the paths
+// of processes, which are absolute.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// isUnderDir reports whether path lies in dir. A relative dir, such as a
+// channel path below a relative hls.base_path, is taken from the monitor's
+// working directory to compare it with the absolute paths of processes.
+func isUnderDir(path, dir string) bool {
+	if filepath.IsAbs(path) {
+		dir = absPath(dir)
+	}
+	rel, err := filepath.Rel(filepath.Clean(dir), path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,49 @@
+package monitor
+
+import (
+	"monitorMultiview/internal/config"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputPathMatcher(t *testing.T) {
+	work := t.TempDir()
+	t.Chdir(work)
+
+	channels := []config.Channel{
+		{ID: "ch01", Path: "output/channel01"},
+		{ID: "ch02", Path: filepath.Join(work, "output", "channel02")},
+		{ID: "ch03", Path: "./output/channel03/"},
+	}
+	tests := []struct {
+		name   string
+		output string
+		cwd    string
+		want   string
+	}{
+		{"absolute output, relative channel path", filepath.Join(work, "output", "channel01", "index.m3u8"), "", "ch01"},
+		{"absolute output, absolute channel path", filepath.Join(work, "output", "channel02", "720p", "index.m3u8"), "", "ch02"},
+		{"relative output resolved against process cwd", "channel03/index.m3u8", filepath.Join(work, "output"), "ch03"},
+		{"sibling directory with a common prefix", filepath.Join(work, "output", "channel010", "index.m3u8"), "", ""},
+		{"outside every channel", "/srv/other/index.m3u8", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proc := &ProcInfo{
+				Args: []string{"ffmpeg", "-i", "udp://:9000", "-f", "hls", tt.output},
+				Cwd:  tt.cwd,
+			}
+			matches := outputPathMatcher{}.Match(proc, ParseFFmpegArgs(proc.Args), channels)
+
+			got := ""
+			if len(matches) > 1 {
+				t.Fatalf("ambiguous match: %v", matches)
+			} else if len(matches) == 1 {
+				got = matches[0].ID
+			}
+			if got != tt.want {
+				t.Errorf("matched %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
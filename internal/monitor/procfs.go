@@ -0,0 +1,242 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultProcRoot is the procfs mount point used by NewFFmpegMonitor.
+const DefaultProcRoot = "/proc"
+
+// ClockTicksPerSecond is USER_HZ, the unit of the times in /proc/<pid>/stat.
+// It is 100 on every Linux architecture we ship for.
+const ClockTicksPerSecond = 100
+
+// ProcInfo is a snapshot of a single process as read from procfs.
+type ProcInfo struct {
+	PID        int
+	PPID       int
+	Name       string
+	State      string
+	Args       []string
+	Cwd        string
+	UTime      uint64 // clock ticks spent in user mode
+	STime      uint64 // clock ticks spent in kernel mode
+	StartTime  uint64 // clock ticks after boot
+	NumThreads int
+	RSS        int64 // resident set size in bytes
+}
+
+// ProcScanner reads process information directly from a procfs tree.
+// Root may point at a fake directory laid out like /proc for testing.
+type ProcScanner struct {
+	Root string
+
+	bootTime time.Time
+}
+
+func NewProcScanner(root string) *ProcScanner {
+	if root == "" {
+		root = DefaultProcRoot
+	}
+	return &ProcScanner{Root: root}
+}
+
+// Scan returns every process under Root. Processes that exit while being
+// read are skipped rather than reported as errors.
+func (s *ProcScanner) Scan() ([]*ProcInfo, error) {
+	entries, err := os.ReadDir(s.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proc root %s: %w", s.Root, err)
+	}
+
+	var procs []*ProcInfo
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid <= 0 {
+			continue
+		}
+
+		proc, err := s.ReadProcess(pid)
+		if err != nil {
+			continue
+		}
+		procs = append(procs, proc)
+	}
+	return procs, nil
+}
+
+// ReadProcess reads cmdline, stat and status for a single pid.
+func (s *ProcScanner) ReadProcess(pid int) (*ProcInfo, error) {
+	dir := filepath.Join(s.Root, strconv.Itoa(pid))
+
+	cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+
+	proc := &ProcInfo{
+		PID:  pid,
+		Args: parseCmdline(cmdline),
+	}
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	if err := parseStat(stat, proc); err != nil {
+		return nil, fmt.Errorf("pid %d: %w", pid, err)
+	}
+
+	// cwd is unreadable for other users' processes unless we are privileged
+	if cwd, err := os.Readlink(filepath.Join(dir, "cwd")); err == nil {
+		proc.Cwd = cwd
+	}
+
+	// status is optional; when present it gives the untruncated name and VmRSS
+	if f, err := os.Open(filepath.Join(dir, "status")); err == nil {
+		parseStatus(f, proc)
+		f.Close()
+	}
+
+	return proc, nil
+}
+
+// BootTime returns the system boot time from the btime line of <root>/stat.
+func (s *ProcScanner) BootTime() (time.Time, error) {
+	if !s.bootTime.IsZero() {
+		return s.bootTime, nil
+	}
+
+	f, err := os.Open(filepath.Join(s.Root, "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "btime ")
+		if !ok {
+			continue
+		}
+		secs, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("malformed btime: %w", err)
+		}
+		s.bootTime = time.Unix(secs, 0)
+		return s.bootTime, nil
+	}
+	return time.Time{}, fmt.Errorf("btime not found in %s/stat", s.Root)
+}
+
+// CountFDs returns the number of open file descriptors of pid.
+func (s *ProcScanner) CountFDs(pid int) (int, error) {
+	entries, err := os.ReadDir(filepath.Join(s.Root, strconv.Itoa(pid), "fd"))
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// ReadEnviron returns the environment of pid. It usually requires running
+// as the same user as the process.
+func (s *ProcScanner) ReadEnviron(pid int) (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(s.Root, strconv.Itoa(pid), "environ"))
+	if err != nil {
+		return nil, err
+	}
+
+	env := make(map[string]string)
+	for _, entry := range strings.Split(string(data), "\x00") {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			env[key] = value
+		}
+	}
+	return env, nil
+}
+
+// parseCmdline splits a NUL-separated argv. Processes that rewrite their
+// title (or are started via `exec -a`) may expose the whole command line as
+// a single argv[0], in which case it is split on whitespace. With more than
+// one argument a space in argv[0] is part of the binary path.
+func parseCmdline(data []byte) []string {
+	data = bytes.TrimRight(data, "\x00")
+	if len(data) == 0 {
+		return nil
+	}
+
+	args := strings.Split(string(data), "\x00")
+	if len(args) == 1 && strings.ContainsAny(args[0], " \t") {
+		args = strings.Fields(args[0])
+	}
+	return args
+}
+
+func parseStat(data []byte, proc *ProcInfo) error {
+	// comm is wrapped in parentheses and may itself contain spaces or ')'
+	open := bytes.IndexByte(data, '(')
+	end := bytes.LastIndexByte(data, ')')
+	if open < 0 || end < open {
+		return fmt.Errorf("malformed stat")
+	}
+	proc.Name = string(data[open+1 : end])
+
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 22 {
+		return fmt.Errorf("short stat: %d fields", len(fields))
+	}
+
+	// Field indices below are offset from stat(5) numbering by 3 (pid, comm, state)
+	proc.State = fields[0]
+	proc.PPID, _ = strconv.Atoi(fields[1])
+	proc.UTime, _ = strconv.ParseUint(fields[11], 10, 64)
+	proc.STime, _ = strconv.ParseUint(fields[12], 10, 64)
+	proc.NumThreads, _ = strconv.Atoi(fields[17])
+	proc.StartTime, _ = strconv.ParseUint(fields[19], 10, 64)
+	if pages, err := strconv.ParseInt(fields[21], 10, 64); err == nil {
+		proc.RSS = pages * int64(os.Getpagesize())
+	}
+	return nil
+}
+
+func parseStatus(f *os.File, proc *ProcInfo) {
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "Name":
+			proc.Name = value
+		case "Threads":
+			if n, err := strconv.Atoi(value); err == nil {
+				proc.NumThreads = n
+			}
+		case "VmRSS":
+			kb, err := strconv.ParseInt(strings.TrimSuffix(value, " kB"), 10, 64)
+			if err == nil {
+				proc.RSS = kb * 1024
+			}
+		}
+	}
+}
+
+// IsFFmpeg reports whether the process looks like an ffmpeg binary.
+func (p *ProcInfo) IsFFmpeg() bool {
+	if len(p.Args) == 0 {
+		// zombies keep their comm but lose argv
+		return p.Name == "ffmpeg"
+	}
+	base := filepath.Base(p.Args[0])
+	return base == "ffmpeg" || strings.HasPrefix(base, "ffmpeg.") || strings.HasPrefix(base, "ffmpeg-")
+}
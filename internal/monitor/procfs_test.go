@@ -0,0 +1,210 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestParseCmdline(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"only NULs", "\x00\x00", nil},
+		{"argv", "ffmpeg\x00-i\x00udp://:9000\x00", []string{"ffmpeg", "-i", "udp://:9000"}},
+		{"no trailing NUL", "ffmpeg\x00-version", []string{"ffmpeg", "-version"}},
+		{"empty argument kept", "ffmpeg\x00-metadata\x00\x00-y\x00", []string{"ffmpeg", "-metadata", "", "-y"}},
+		{"space in binary path", "/opt/my tools/ffmpeg\x00-i\x00x\x00", []string{"/opt/my tools/ffmpeg", "-i", "x"}},
+		{"rewritten title", "ffmpeg -i udp://:9000 -f hls out.m3u8", []string{"ffmpeg", "-i", "udp://:9000", "-f", "hls", "out.m3u8"}},
+		{"rewritten title with NUL", "ffmpeg  -i\tx\x00", []string{"ffmpeg", "-i", "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCmdline([]byte(tt.data)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCmdline(%q) = %q, want %q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+// statLine builds a /proc/<pid>/stat line with the fields parseStat reads.
+func statLine(pid int, comm, state string, ppid int, utime, stime uint64, threads int, start uint64, rssPages int64) string {
+	return strconv.Itoa(pid) + " (" + comm + ") " + state + " " + strconv.Itoa(ppid) +
+		" 100 100 0 -1 4194560 500 0 0 0 " +
+		strconv.FormatUint(utime, 10) + " " + strconv.FormatUint(stime, 10) +
+		" 0 0 20 0 " + strconv.Itoa(threads) + " 0 " + strconv.FormatUint(start, 10) +
+		" 123456789 " + strconv.FormatInt(rssPages, 10) + " 18446744073709551615 1 1 0 0 0\n"
+}
+
+func TestParseStat(t *testing.T) {
+	page := int64(os.Getpagesize())
+	tests := []struct {
+		name    string
+		data    string
+		want    ProcInfo
+		wantErr bool
+	}{
+		{
+			name: "ffmpeg",
+			data: statLine(1234, "ffmpeg", "S", 1, 250, 50, 8, 5000, 10),
+			want: ProcInfo{Name: "ffmpeg", State: "S", PPID: 1, UTime: 250, STime: 50, NumThreads: 8, StartTime: 5000, RSS: 10 * page},
+		},
+		{
+			name: "comm with spaces and parentheses",
+			data: statLine(7, "ff (mpeg) x", "R", 2, 1, 2, 3, 4, 5),
+			want: ProcInfo{Name: "ff (mpeg) x", State: "R", PPID: 2, UTime: 1, STime: 2, NumThreads: 3, StartTime: 4, RSS: 5 * page},
+		},
+		{
+			name: "zombie",
+			data: statLine(9, "ffmpeg", "Z", 1, 0, 0, 1, 42, 0),
+			want: ProcInfo{Name: "ffmpeg", State: "Z", PPID: 1, NumThreads: 1, StartTime: 42},
+		},
+		{name: "no comm", data: "1234 ffmpeg S 1", wantErr: true},
+		{name: "short", data: "1234 (ffmpeg) S 1 100 100 0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got ProcInfo
+			err := parseStat([]byte(tt.data), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseStat(%q) succeeded, want an error", tt.data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseStat: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseStat = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeProcess is a process to lay out under a fake proc root.
+type fakeProcess struct {
+	pid     int
+	cmdline string
+	stat    string
+	status  string
+	cwd     string
+	fds     int
+}
+
+// writeFakeProc creates a directory laid out like /proc holding procs.
+func writeFakeProc(t *testing.T, procs ...fakeProcess) string {
+	t.Helper()
+	root := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(root, "stat"), "cpu  1 2 3 4\nbtime 1700000000\nprocesses 42\n")
+	for _, p := range procs {
+		dir := filepath.Join(root, strconv.Itoa(p.pid))
+		write(filepath.Join(dir, "cmdline"), p.cmdline)
+		if p.stat != "" {
+			write(filepath.Join(dir, "stat"), p.stat)
+		}
+		if p.status != "" {
+			write(filepath.Join(dir, "status"), p.status)
+		}
+		if p.cwd != "" {
+			if err := os.Symlink(p.cwd, filepath.Join(dir, "cwd")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for fd := 0; fd < p.fds; fd++ {
+			write(filepath.Join(dir, "fd", strconv.Itoa(fd)), "")
+		}
+	}
+	// Entries that are not processes are ignored
+	write(filepath.Join(root, "self", "cmdline"), "")
+	write(filepath.Join(root, "meminfo"), "")
+	return root
+}
+
+func TestProcScannerScan(t *testing.T) {
+	root := writeFakeProc(t,
+		fakeProcess{
+			pid:     100,
+			cmdline: "/usr/bin/ffmpeg\x00-i\x00udp://:8001\x00-f\x00hls\x00/output/channel01/index.m3u8\x00",
+			stat:    statLine(100, "ffmpeg", "S", 1, 300, 100, 12, 2000, 50),
+			status:  "Name:\tffmpeg\nThreads:\t14\nVmRSS:\t  20480 kB\n",
+			cwd:     "/srv/hls",
+			fds:     3,
+		},
+		fakeProcess{
+			pid:     200,
+			cmdline: "/bin/sh\x00-c\x00sleep 1\x00",
+			stat:    statLine(200, "sh", "S", 1, 0, 0, 1, 3000, 1),
+		},
+		// exited between listing the directory and reading stat
+		fakeProcess{pid: 300, cmdline: "ffmpeg\x00"},
+	)
+
+	scanner := NewProcScanner(root)
+	procs, err := scanner.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(procs) != 2 {
+		t.Fatalf("Scan returned %d processes, want 2", len(procs))
+	}
+
+	byPID := make(map[int]*ProcInfo)
+	for _, p := range procs {
+		byPID[p.PID] = p
+	}
+	ffmpeg := byPID[100]
+	if ffmpeg == nil {
+		t.Fatal("pid 100 not scanned")
+	}
+	want := &ProcInfo{
+		PID:        100,
+		PPID:       1,
+		Name:       "ffmpeg",
+		State:      "S",
+		Args:       []string{"/usr/bin/ffmpeg", "-i", "udp://:8001", "-f", "hls", "/output/channel01/index.m3u8"},
+		Cwd:        "/srv/hls",
+		UTime:      300,
+		STime:      100,
+		StartTime:  2000,
+		NumThreads: 14,
+		RSS:        20480 * 1024,
+	}
+	if !reflect.DeepEqual(ffmpeg, want) {
+		t.Errorf("pid 100 = %+v, want %+v", ffmpeg, want)
+	}
+	if !ffmpeg.IsFFmpeg() {
+		t.Error("pid 100 is not recognised as ffmpeg")
+	}
+	if sh := byPID[200]; sh == nil || sh.IsFFmpeg() {
+		t.Errorf("pid 200 = %+v, want a scanned non-ffmpeg process", sh)
+	}
+
+	if n, err := scanner.CountFDs(100); err != nil || n != 3 {
+		t.Errorf("CountFDs(100) = %d, %v, want 3", n, err)
+	}
+	boot, err := scanner.BootTime()
+	if err != nil || boot.Unix() != 1700000000 {
+		t.Errorf("BootTime() = %v, %v, want 1700000000", boot, err)
+	}
+}
+
+func TestProcScannerMissingRoot(t *testing.T) {
+	if _, err := NewProcScanner(filepath.Join(t.TempDir(), "missing")).Scan(); err == nil {
+		t.Error("Scan of a missing root succeeded, want an error")
+	}
+}
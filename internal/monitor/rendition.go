@@ -0,0 +1,134 @@
+package monitor
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+const (
+	RenditionVariant    = "VARIANT"
+	RenditionIFrame     = "I-FRAME"
+	RenditionStandalone = "STANDALONE"
+	// RenditionExpected is a configured rendition that was not found
+	RenditionExpected = "EXPECTED"
+)
+
+// Rendition is one media playlist of a package, either referenced from the
+// master playlist or found on its own.
+type Rendition struct {
+	// URI as written in the master playlist
+	URI string `json:"uri"`
+	// Playlist is the path relative to the package directory; empty when the
+	// URI points outside of it
+	Playlist   string `json:"playlist"`
+	Type       string `json:"type"`
+	Bandwidth  int    `json:"bandwidth"`
+	Resolution string `json:"resolution"`
+	Codecs     string `json:"codecs"`
+	Name       string `json:"name"`
+
+	SegmentCount   int            `json:"segment_count"`
+	LatestSegment  string         `json:"latest_segment"`
+	TargetDuration int            `json:"target_duration"`
+	LastUpdate     time.Time      `json:"last_update"`
+	Ended          bool           `json:"ended"`
+	Health         string         `json:"health"`
+	HealthReason   string         `json:"health_reason"`
+	Sequence       *SequenceState `json:"sequence"`
+}
+
+// playlistRef is a URI a master playlist points at.
+type playlistRef struct {
+	uri        string
+	kind       string
+	bandwidth  int
+	resolution string
+	codecs     string
+	name       string
+}
+
+// masterReferences lists the variant and rendition playlists of a master.
+func masterReferences(info *M3U8Info) []playlistRef {
+	var refs []playlistRef
+	for _, variant := range info.Variants {
+		kind := RenditionVariant
+		if variant.IFrameOnly {
+			kind = RenditionIFrame
+		}
+		refs = append(refs, playlistRef{
+			uri:        variant.URI,
+			kind:       kind,
+			bandwidth:  variant.Bandwidth,
+			resolution: variant.Resolution,
+			codecs:     variant.Codecs,
+		})
+	}
+	for _, media := range info.Media {
+		if media.URI == "" {
+			continue // muxed into the variant streams
+		}
+		refs = append(refs, playlistRef{
+			uri:  media.URI,
+			kind: media.Type,
+			name: media.Name,
+		})
+	}
+	return refs
+}
+
+// resolvePlaylistRef turns a URI from the master playlist at masterName into
+// a path relative to the package directory.
+func resolvePlaylistRef(masterName, uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "" || u.Host != "" || path.IsAbs(u.Path) {
+		return "", false
+	}
+	resolved := path.Join(path.Dir(filepath.ToSlash(masterName)), u.Path)
+	return filepath.FromSlash(resolved), true
+}
+
+// pickMaster chooses the master playlist of a package. A configured name
+// must match exactly; otherwise master.m3u8 is preferred, then the one
+// listing the most variants.
+func pickMaster(masters []*parsedPlaylist, configured string) *parsedPlaylist {
+	if configured != "" {
+		for _, pl := range masters {
+			if pl.name == filepath.Clean(configured) {
+				return pl
+			}
+		}
+		return nil
+	}
+
+	var best *parsedPlaylist
+	for _, pl := range masters {
+		if filepath.Base(pl.name) == "master.m3u8" {
+			return pl
+		}
+		if best == nil || len(pl.info.Variants) > len(best.info.Variants) {
+			best = pl
+		}
+	}
+	return best
+}
+
+// hasRendition reports whether a configured rendition, given as a playlist
+// path relative to the package, is among renditions.
+func hasRendition(renditions []*Rendition, playlist string) bool {
+	for _, rendition := range renditions {
+		if rendition.Playlist == filepath.Clean(playlist) || rendition.URI == playlist {
+			return true
+		}
+	}
+	return false
+}
+
+// parsedPlaylist is a playlist file read during one package scan.
+type parsedPlaylist struct {
+	name    string
+	path    string
+	info    *M3U8Info
+	modTime time.Time
+}
@@ -0,0 +1,125 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	SequenceAdvancing = "ADVANCING"
+	SequenceStalled   = "STALLED"
+	SequenceRewound   = "REWOUND"
+	SequenceJumped    = "JUMPED"
+)
+
+// SequenceState is the media-sequence progression of one media playlist.
+// EndSequence is the sequence number following the last listed segment, which
+// also advances for EVENT playlists whose MEDIA-SEQUENCE never changes.
+type SequenceState struct {
+	Playlist      string    `json:"playlist"`
+	MediaSequence int       `json:"media_sequence"`
+	EndSequence   int       `json:"end_sequence"`
+	Status        string    `json:"status"`
+	Detail        string    `json:"detail"`
+	LastAdvance   time.Time `json:"last_advance"`
+	LastChecked   time.Time `json:"last_checked"`
+	Rewinds       int       `json:"rewinds"`
+	Jumps         int       `json:"jumps"`
+}
+
+// sequenceTracker remembers #EXT-X-MEDIA-SEQUENCE per playlist path between
+// scans.
+type sequenceTracker struct {
+	states map[string]*SequenceState
+}
+
+func newSequenceTracker() *sequenceTracker {
+	return &sequenceTracker{
+		states: make(map[string]*SequenceState),
+	}
+}
+
+// observe records the media sequence seen in playlist and classifies the
+// change since the previous scan. The playlist is stalled when it has not
+// advanced for stallFactor target durations.
+func (t *sequenceTracker) observe(playlist string, sequence, segmentCount, targetDuration int, stallFactor float64, now time.Time) SequenceState {
+	target := time.Duration(targetDuration) * time.Second
+	if target <= 0 {
+		target = defaultTargetDuration
+	}
+
+	state, ok := t.states[playlist]
+	if !ok {
+		state = &SequenceState{
+			Playlist:      playlist,
+			MediaSequence: sequence,
+			EndSequence:   sequence + segmentCount,
+			Status:        SequenceAdvancing,
+			LastAdvance:   now,
+			LastChecked:   now,
+		}
+		t.states[playlist] = state
+		return *state
+	}
+
+	elapsed := now.Sub(state.LastChecked)
+	previous := state.MediaSequence
+	end := sequence + segmentCount
+
+	switch {
+	case sequence < previous:
+		state.Rewinds++
+		state.Status = SequenceRewound
+		state.Detail = fmt.Sprintf("went back from %d to %d at %s", previous, sequence, now.Format("15:04:05"))
+		state.LastAdvance = now
+
+	case end > state.EndSequence:
+		// At most one segment per target duration can have been produced,
+		// plus one for segments finished just around the previous scan.
+		allowed := int(math.Ceil(elapsed.Seconds()/target.Seconds())) + 1
+		if delta := end - state.EndSequence; delta > allowed {
+			state.Jumps++
+			state.Status = SequenceJumped
+			state.Detail = fmt.Sprintf("jumped by %d in %s (expected at most %d)", delta, FormatDuration(elapsed), allowed)
+		} else {
+			state.Status = SequenceAdvancing
+			state.Detail = ""
+		}
+		state.LastAdvance = now
+
+	default:
+		stall := time.Duration(float64(target) * stallFactor)
+		if since := now.Sub(state.LastAdvance); since > stall {
+			state.Status = SequenceStalled
+			state.Detail = fmt.Sprintf("stuck at %d for %s", end-1, FormatDuration(since))
+		}
+	}
+
+	state.MediaSequence = sequence
+	state.EndSequence = end
+	state.LastChecked = now
+	return *state
+}
+
+// get returns a copy of the last recorded state of playlist.
+func (t *sequenceTracker) get(playlist string) *SequenceState {
+	state, ok := t.states[playlist]
+	if !ok {
+		return nil
+	}
+	snapshot := *state
+	return &snapshot
+}
+
+// sequenceHealth maps a sequence status onto the HLS health scale.
+func sequenceHealth(status string) string {
+	switch status {
+	case SequenceStalled:
+		return HealthStale
+	case SequenceRewound, SequenceJumped:
+		return HealthWarn
+	default:
+		return HealthOK
+	}
+}
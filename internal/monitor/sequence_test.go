@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"monitorMultiview/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSequenceTracker(t *testing.T) {
+	type scan struct {
+		after    time.Duration // since the previous scan
+		sequence int
+		segments int
+	}
+	tests := []struct {
+		name   string
+		scans  []scan
+		status string
+		rewind int
+		jumps  int
+	}{
+		{
+			name:   "first sighting",
+			scans:  []scan{{0, 100, 5}},
+			status: SequenceAdvancing,
+		},
+		{
+			name:   "advancing",
+			scans:  []scan{{0, 100, 5}, {6 * time.Second, 101, 5}, {6 * time.Second, 102, 5}},
+			status: SequenceAdvancing,
+		},
+		{
+			name:   "event playlist growing",
+			scans:  []scan{{0, 0, 5}, {6 * time.Second, 0, 6}, {6 * time.Second, 0, 7}},
+			status: SequenceAdvancing,
+		},
+		{
+			name:   "unchanged within the threshold",
+			scans:  []scan{{0, 100, 5}, {6 * time.Second, 100, 5}, {6 * time.Second, 100, 5}},
+			status: SequenceAdvancing,
+		},
+		{
+			name:   "stalled past the threshold",
+			scans:  []scan{{0, 100, 5}, {10 * time.Second, 100, 5}, {10 * time.Second, 100, 5}},
+			status: SequenceStalled,
+		},
+		{
+			name:   "advancing again after a stall",
+			scans:  []scan{{0, 100, 5}, {20 * time.Second, 100, 5}, {6 * time.Second, 101, 5}},
+			status: SequenceAdvancing,
+		},
+		{
+			name:   "rewound",
+			scans:  []scan{{0, 100, 5}, {6 * time.Second, 3, 5}},
+			status: SequenceRewound,
+			rewind: 1,
+		},
+		{
+			name:   "jumped",
+			scans:  []scan{{0, 100, 5}, {6 * time.Second, 110, 5}},
+			status: SequenceJumped,
+			jumps:  1,
+		},
+		{
+			name:   "catching up after a long scan gap is not a jump",
+			scans:  []scan{{0, 100, 5}, {60 * time.Second, 110, 5}},
+			status: SequenceAdvancing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newSequenceTracker()
+			now := time.Now()
+			var state SequenceState
+			for _, s := range tt.scans {
+				now = now.Add(s.after)
+				// stalled after 3 target durations of 6s
+				state = tracker.observe("index.m3u8", s.sequence, s.segments, 6, 3, now)
+			}
+			if state.Status != tt.status || state.Rewinds != tt.rewind || state.Jumps != tt.jumps {
+				t.Errorf("state = %+v, want %s with %d rewinds and %d jumps", state, tt.status, tt.rewind, tt.jumps)
+			}
+			if (state.Status == SequenceAdvancing) != (state.Detail == "") {
+				t.Errorf("status %s with detail %q", state.Status, state.Detail)
+			}
+			if got := tracker.get("index.m3u8"); got == nil || *got != state {
+				t.Errorf("get() = %+v, want the last state", got)
+			}
+		})
+	}
+}
+
+func TestEndedPlaylistNotStalled(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "channel01")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	playlist := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:1\n#EXTINF:6.0,\nseg1.ts\n#EXT-X-ENDLIST\n"
+	old := time.Now().Add(-time.Hour)
+	for name, content := range map[string]string{"index.m3u8": playlist, "seg1.ts": "ts"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.HLS.BasePath = base
+	cfg.HLS.WatchMode = "poll"
+	cfg.Channels.Count = 1
+	m := NewHLSMonitor(cfg)
+	defer m.Close()
+
+	for range 2 {
+		m.ScanDirectories()
+		m.EvaluatePackages()
+	}
+	pkg := evaluated(m, "ch01")
+	if pkg.Health != HealthOK || len(pkg.Sequences) != 0 {
+		t.Errorf("package health %s with sequences %+v, want OK and no sequence tracking", pkg.Health, pkg.Sequences)
+	}
+	if len(pkg.Renditions) != 1 || !pkg.Renditions[0].Ended {
+		t.Errorf("renditions = %+v, want one ended rendition", pkg.Renditions)
+	}
+}
@@ -0,0 +1,229 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	SeverityError   = "ERROR"
+	SeverityWarning = "WARN"
+	SeverityInfo    = "INFO"
+)
+
+// Validation rule IDs
+const (
+	RuleFirstLine       = "HLS-001" // #EXTM3U must be the first line
+	RuleMalformedTag    = "HLS-002" // tag could not be parsed
+	RuleSegmentDuration = "HLS-003" // rounded EXTINF must not exceed TARGETDURATION
+	RuleMediaSequence   = "HLS-004" // MEDIA-SEQUENCE must not decrease
+	RuleVersion         = "HLS-005" // EXT-X-VERSION must cover the features used
+	RuleMissingFile     = "HLS-006" // referenced segment/playlist must exist
+	RuleDuplicateURI    = "HLS-007" // URIs must be unique
+	RuleMixedPlaylist   = "HLS-008" // master and media tags must not be mixed
+	RuleEmptyPlaylist   = "HLS-009" // a media playlist should list segments
+)
+
+// Finding is a single conformance problem in a playlist.
+type Finding struct {
+	RuleID   string `json:"rule_id"`
+	Severity string `json:"severity"`
+	Playlist string `json:"playlist"`
+	Line     int    `json:"line"`
+	Message  string `json:"message"`
+}
+
+func (f Finding) String() string {
+	location := f.Playlist
+	if f.Line > 0 {
+		location = fmt.Sprintf("%s:%d", f.Playlist, f.Line)
+	}
+	return fmt.Sprintf("[%s %s] %s: %s", f.Severity, f.RuleID, location, f.Message)
+}
+
+// ValidatePlaylist checks a parsed playlist against RFC 8216 rules. dir is
+// the directory relative URIs are resolved against; previous is the state
+// from the last scan of the same playlist, if any.
+func ValidatePlaylist(name string, info *M3U8Info, dir string, previous *SequenceState) []Finding {
+	return validatePlaylist(name, info, dir, previous, fileExists)
+}
+
+// validatePlaylist is ValidatePlaylist with the existence check supplied by
+// the caller, so a directory index can answer it without touching the disk.
+func validatePlaylist(name string, info *M3U8Info, dir string, previous *SequenceState, exists func(path string) bool) []Finding {
+	v := &playlistValidator{name: name, info: info, dir: dir, exists: exists}
+
+	v.checkFirstLine()
+	v.checkParseErrors()
+	v.checkVersion()
+	if info.IsMaster {
+		v.checkMaster()
+	} else {
+		v.checkSegments()
+		v.checkMediaSequence(previous)
+	}
+	return v.findings
+}
+
+type playlistValidator struct {
+	name     string
+	info     *M3U8Info
+	dir      string
+	exists   func(path string) bool
+	findings []Finding
+}
+
+func (v *playlistValidator) add(rule, severity string, line int, format string, args ...interface{}) {
+	v.findings = append(v.findings, Finding{
+		RuleID:   rule,
+		Severity: severity,
+		Playlist: v.name,
+		Line:     line,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *playlistValidator) checkFirstLine() {
+	if !v.info.StartsWithEXTM3U {
+		v.add(RuleFirstLine, SeverityError, 1, "first line is not #EXTM3U")
+	}
+}
+
+func (v *playlistValidator) checkParseErrors() {
+	for _, err := range v.info.Errors {
+		if err.Tag == "#EXTM3U" && err.Line == 1 {
+			continue // reported by RuleFirstLine
+		}
+		v.add(RuleMalformedTag, SeverityError, err.Line, "%s: %v", err.Tag, err.Err)
+	}
+}
+
+// checkVersion derives the minimum EXT-X-VERSION from the features used
+// (RFC 8216 section 7).
+func (v *playlistValidator) checkVersion() {
+	required, reason := 1, ""
+	need := func(version int, why string) {
+		if version > required {
+			required, reason = version, why
+		}
+	}
+
+	for _, seg := range v.info.Segments {
+		if seg.Key != nil {
+			if seg.Key.IV != "" {
+				need(2, "EXT-X-KEY IV attribute")
+			}
+			if seg.Key.KeyFormat != "" || seg.Key.KeyFormatVersions != "" {
+				need(5, "EXT-X-KEY KEYFORMAT attributes")
+			}
+		}
+		if seg.Duration != math.Trunc(seg.Duration) {
+			need(3, "floating-point EXTINF durations")
+		}
+		if seg.ByteRange != nil {
+			need(4, "EXT-X-BYTERANGE")
+		}
+		if seg.Map != nil {
+			if v.info.IFramesOnly {
+				need(5, "EXT-X-MAP")
+			} else {
+				need(6, "EXT-X-MAP in a non I-frame playlist")
+			}
+		}
+	}
+	if v.info.IFramesOnly {
+		need(4, "EXT-X-I-FRAMES-ONLY")
+	}
+
+	declared := v.info.Version
+	if declared == 0 {
+		declared = 1
+	}
+	if declared < required {
+		v.add(RuleVersion, SeverityError, 0, "version %d declared but %s requires version %d", declared, reason, required)
+	}
+}
+
+func (v *playlistValidator) checkSegments() {
+	if len(v.info.Variants) > 0 || len(v.info.Media) > 0 {
+		v.add(RuleMixedPlaylist, SeverityError, 0, "media playlist contains master playlist tags")
+	}
+	if len(v.info.Segments) == 0 && !v.info.EndList {
+		v.add(RuleEmptyPlaylist, SeverityWarning, 0, "media playlist lists no segments")
+	}
+
+	target := v.info.TargetDuration
+	seen := make(map[string]int)
+	for _, seg := range v.info.Segments {
+		if target > 0 && int(math.Round(seg.Duration)) > target {
+			v.add(RuleSegmentDuration, SeverityError, seg.Line,
+				"%s lasts %.3fs, above target duration %ds", seg.URI, seg.Duration, target)
+		}
+
+		// Byte-range segments legitimately share a URI
+		if seg.ByteRange == nil {
+			if first, dup := seen[seg.URI]; dup {
+				v.add(RuleDuplicateURI, SeverityWarning, seg.Line, "%s already listed on line %d", seg.URI, first)
+			} else {
+				seen[seg.URI] = seg.Line
+			}
+		}
+
+		v.checkExists(seg.URI, seg.Line)
+	}
+}
+
+func (v *playlistValidator) checkMaster() {
+	if len(v.info.Segments) > 0 {
+		v.add(RuleMixedPlaylist, SeverityError, v.info.Segments[0].Line, "master playlist contains media segments")
+	}
+
+	seen := make(map[string]int)
+	for _, variant := range v.info.Variants {
+		if first, dup := seen[variant.URI]; dup && !variant.IFrameOnly {
+			v.add(RuleDuplicateURI, SeverityWarning, variant.Line, "variant %s already listed on line %d", variant.URI, first)
+		} else {
+			seen[variant.URI] = variant.Line
+		}
+		v.checkExists(variant.URI, variant.Line)
+	}
+	for _, media := range v.info.Media {
+		if media.URI != "" {
+			v.checkExists(media.URI, media.Line)
+		}
+	}
+}
+
+func (v *playlistValidator) checkMediaSequence(previous *SequenceState) {
+	if previous == nil {
+		return
+	}
+	if v.info.MediaSequence < previous.MediaSequence {
+		v.add(RuleMediaSequence, SeverityError, 0,
+			"media sequence went back from %d to %d", previous.MediaSequence, v.info.MediaSequence)
+	}
+}
+
+// checkExists reports relative URIs that do not resolve to a file on disk.
+// Absolute URLs are not fetched.
+func (v *playlistValidator) checkExists(uri string, line int) {
+	if v.dir == "" {
+		return
+	}
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "" || u.Host != "" || path.IsAbs(u.Path) {
+		return
+	}
+	if !v.exists(filepath.Join(v.dir, filepath.FromSlash(u.Path))) {
+		v.add(RuleMissingFile, SeverityError, line, "%s does not exist", uri)
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
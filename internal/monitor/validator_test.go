@@ -0,0 +1,179 @@
+package monitor
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+const validatorDir = "/output/channel01"
+
+// existing answers the existence check from a fixed list of names in
+// validatorDir.
+func existing(names ...string) func(path string) bool {
+	files := make(map[string]bool, len(names))
+	for _, name := range names {
+		files[filepath.Join(validatorDir, name)] = true
+	}
+	return func(path string) bool { return files[path] }
+}
+
+func TestValidatePlaylistRules(t *testing.T) {
+	const media = "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:10\n"
+	tests := []struct {
+		rule     string
+		name     string
+		playlist string
+		previous *SequenceState
+		exists   []string
+		fails    bool
+		line     int
+	}{
+		{
+			rule:     RuleFirstLine,
+			name:     "first line is not EXTM3U",
+			playlist: "#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.0,\nseg10.ts\n",
+			exists:   []string{"seg10.ts"},
+			fails:    true,
+			line:     1,
+		},
+		{
+			rule:     RuleFirstLine,
+			name:     "starts with EXTM3U",
+			playlist: media + "#EXTINF:6.0,\nseg10.ts\n",
+			exists:   []string{"seg10.ts"},
+		},
+		{
+			rule:     RuleMalformedTag,
+			name:     "malformed program date-time",
+			playlist: media + "#EXT-X-PROGRAM-DATE-TIME:yesterday\n#EXTINF:6.0,\nseg10.ts\n",
+			exists:   []string{"seg10.ts"},
+			fails:    true,
+			line:     5,
+		},
+		{
+			rule:     RuleMalformedTag,
+			name:     "well-formed tags",
+			playlist: media + "#EXT-X-PROGRAM-DATE-TIME:2024-05-01T12:00:00Z\n#EXTINF:6.0,\nseg10.ts\n",
+			exists:   []string{"seg10.ts"},
+		},
+		{
+			rule:     RuleSegmentDuration,
+			name:     "segment longer than the target",
+			playlist: media + "#EXTINF:6.0,\nseg10.ts\n#EXTINF:6.6,\nseg11.ts\n",
+			exists:   []string{"seg10.ts", "seg11.ts"},
+			fails:    true,
+			line:     7,
+		},
+		{
+			rule:     RuleSegmentDuration,
+			name:     "segment rounds down to the target",
+			playlist: media + "#EXTINF:6.4,\nseg10.ts\n",
+			exists:   []string{"seg10.ts"},
+		},
+		{
+			rule:     RuleMediaSequence,
+			name:     "media sequence went back",
+			playlist: media + "#EXTINF:6.0,\nseg10.ts\n",
+			previous: &SequenceState{MediaSequence: 12},
+			exists:   []string{"seg10.ts"},
+			fails:    true,
+		},
+		{
+			rule:     RuleMediaSequence,
+			name:     "media sequence unchanged",
+			playlist: media + "#EXTINF:6.0,\nseg10.ts\n",
+			previous: &SequenceState{MediaSequence: 10},
+			exists:   []string{"seg10.ts"},
+		},
+		{
+			rule:     RuleVersion,
+			name:     "fractional durations in version 2",
+			playlist: "#EXTM3U\n#EXT-X-VERSION:2\n#EXT-X-TARGETDURATION:6\n#EXTINF:5.5,\nseg10.ts\n",
+			exists:   []string{"seg10.ts"},
+			fails:    true,
+		},
+		{
+			rule:     RuleVersion,
+			name:     "EXT-X-MAP in version 6",
+			playlist: "#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:6\n#EXT-X-MAP:URI=\"init.mp4\"\n#EXTINF:5.5,\nseg10.m4s\n",
+			exists:   []string{"seg10.m4s"},
+		},
+		{
+			rule:     RuleMissingFile,
+			name:     "segment missing",
+			playlist: media + "#EXTINF:6.0,\nseg10.ts\n#EXTINF:6.0,\nseg11.ts\n",
+			exists:   []string{"seg10.ts"},
+			fails:    true,
+			line:     7,
+		},
+		{
+			rule:     RuleMissingFile,
+			name:     "segments present and absolute URLs skipped",
+			playlist: media + "#EXTINF:6.0,\nsub/seg10.ts\n#EXTINF:6.0,\nhttps://cdn.example/seg11.ts\n#EXTINF:6.0,\n/abs/seg12.ts\n",
+			exists:   []string{"sub/seg10.ts"},
+		},
+		{
+			rule:     RuleDuplicateURI,
+			name:     "segment listed twice",
+			playlist: media + "#EXTINF:6.0,\nseg10.ts\n#EXTINF:6.0,\nseg10.ts\n",
+			exists:   []string{"seg10.ts"},
+			fails:    true,
+			line:     7,
+		},
+		{
+			rule:     RuleDuplicateURI,
+			name:     "byte ranges of one file",
+			playlist: "#EXTM3U\n#EXT-X-VERSION:4\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.0,\n#EXT-X-BYTERANGE:1000@0\nall.ts\n#EXTINF:6.0,\n#EXT-X-BYTERANGE:1000\nall.ts\n",
+			exists:   []string{"all.ts"},
+		},
+		{
+			rule:     RuleMixedPlaylist,
+			name:     "master playlist with segments",
+			playlist: "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000\nlow.m3u8\n#EXTINF:6.0,\nseg10.ts\n",
+			exists:   []string{"low.m3u8", "seg10.ts"},
+			fails:    true,
+			line:     4,
+		},
+		{
+			rule:     RuleMixedPlaylist,
+			name:     "master playlist",
+			playlist: "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000\nlow.m3u8\n#EXT-X-STREAM-INF:BANDWIDTH=2000000\nhigh.m3u8\n",
+			exists:   []string{"low.m3u8", "high.m3u8"},
+		},
+		{
+			rule:     RuleEmptyPlaylist,
+			name:     "live playlist without segments",
+			playlist: media,
+			fails:    true,
+		},
+		{
+			rule:     RuleEmptyPlaylist,
+			name:     "ended playlist without segments",
+			playlist: media + "#EXT-X-ENDLIST\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule+" "+tt.name, func(t *testing.T) {
+			info := parse(t, tt.playlist)
+			findings := validatePlaylist("index.m3u8", info, validatorDir, tt.previous, existing(tt.exists...))
+
+			var matched []Finding
+			for _, f := range findings {
+				if f.RuleID == tt.rule {
+					matched = append(matched, f)
+				} else {
+					t.Errorf("unexpected finding %s", f)
+				}
+			}
+			switch {
+			case !tt.fails && len(matched) > 0:
+				t.Errorf("unexpected finding %s", matched[0])
+			case tt.fails && len(matched) != 1:
+				t.Errorf("%d %s findings, want 1", len(matched), tt.rule)
+			case tt.fails && matched[0].Line != tt.line:
+				t.Errorf("finding %s on line %d, want %d", matched[0], matched[0].Line, tt.line)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	WatchAuto    = "auto"
+	WatchInotify = "inotify"
+	WatchPoll    = "poll"
+)
+
+// fsEvent is a change below a watched directory. Overflow means events were
+// dropped and the directories have to be rescanned.
+type fsEvent struct {
+	Path     string
+	Overflow bool
+}
+
+// dirWatcher reports changes in a set of directories. Watches are not
+// recursive; subdirectories have to be added on their own.
+type dirWatcher interface {
+	Add(dir string) error
+	Events() <-chan fsEvent
+	Close() error
+}
+
+// newDirWatcher returns a watcher for the given mode, or nil if the channel
+// directories should be polled. In auto mode roots on filesystems that do
+// not deliver change events (network and FUSE mounts) select polling.
+func newDirWatcher(mode string, roots []string) (dirWatcher, error) {
+	switch mode {
+	case WatchPoll:
+		return nil, nil
+	case WatchAuto:
+		for _, root := range roots {
+			if err := checkWatchable(existingAncestor(root)); err != nil {
+				return nil, err
+			}
+		}
+	case WatchInotify:
+	default:
+		return nil, fmt.Errorf("unknown watch mode: %q", mode)
+	}
+	return newInotifyWatcher()
+}
+
+// existingAncestor returns path or the closest parent directory that exists,
+// so a channel directory created later can be checked up front.
+func existingAncestor(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
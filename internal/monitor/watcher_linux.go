@@ -0,0 +1,136 @@
+//go:build linux
+
+package monitor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+	syscall.IN_DELETE | syscall.IN_DELETE_SELF | syscall.IN_ONLYDIR
+
+// Filesystems where inotify misses changes made by other hosts, by
+// statfs(2) f_type
+var pollOnlyFilesystems = map[uint32]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xfe534d42: "smb2",
+	0xff534d42: "cifs",
+	0x65735546: "fuse",
+}
+
+type inotifyWatcher struct {
+	file    *os.File
+	fd      int
+	mu      sync.Mutex
+	watches map[int32]string
+	events  chan fsEvent
+}
+
+func newInotifyWatcher() (dirWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify_init1: %w", err)
+	}
+
+	// A non-blocking descriptor is served by the runtime poller, so Close
+	// wakes up the pending Read
+	w := &inotifyWatcher{
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		fd:      fd,
+		watches: make(map[int32]string),
+		events:  make(chan fsEvent, 1024),
+	}
+	go w.readEvents()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Add(dir string) error {
+	wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask)
+	if err != nil {
+		return fmt.Errorf("watch %s: %w", dir, err)
+	}
+	w.mu.Lock()
+	w.watches[int32(wd)] = dir
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *inotifyWatcher) Events() <-chan fsEvent {
+	return w.events
+}
+
+func (w *inotifyWatcher) Close() error {
+	return w.file.Close()
+}
+
+func (w *inotifyWatcher) readEvents() {
+	defer close(w.events)
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			// struct inotify_event { int wd; uint32 mask, cookie, len; char name[]; }
+			wd := int32(binary.NativeEndian.Uint32(buf[offset:]))
+			mask := binary.NativeEndian.Uint32(buf[offset+4:])
+			nameLen := int(binary.NativeEndian.Uint32(buf[offset+12:]))
+			offset += syscall.SizeofInotifyEvent
+
+			var name string
+			if nameLen > 0 && offset+nameLen <= n {
+				name = strings.TrimRight(string(buf[offset:offset+nameLen]), "\x00")
+			}
+			offset += nameLen
+
+			w.handle(wd, mask, name)
+		}
+	}
+}
+
+func (w *inotifyWatcher) handle(wd int32, mask uint32, name string) {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		w.events <- fsEvent{Overflow: true}
+		return
+	}
+
+	w.mu.Lock()
+	dir, ok := w.watches[wd]
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(w.watches, wd)
+	}
+	w.mu.Unlock()
+	if !ok || mask&syscall.IN_IGNORED != 0 {
+		return
+	}
+
+	path := dir
+	if name != "" {
+		path = filepath.Join(dir, name)
+	}
+	w.events <- fsEvent{Path: path}
+}
+
+// checkWatchable rejects directories whose filesystem does not report
+// remote changes through inotify.
+func checkWatchable(path string) error {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return fmt.Errorf("statfs %s: %w", path, err)
+	}
+	if fsName, ok := pollOnlyFilesystems[uint32(st.Type)]; ok {
+		return fmt.Errorf("%s is on %s, which does not support inotify", path, fsName)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package monitor
+
+import "errors"
+
+var errNoInotify = errors.New("inotify is only available on Linux")
+
+func newInotifyWatcher() (dirWatcher, error) {
+	return nil, errNoInotify
+}
+
+func checkWatchable(path string) error {
+	return errNoInotify
+}
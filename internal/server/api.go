@@ -0,0 +1,223 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"monitorMultiview/internal/config"
+	"monitorMultiview/internal/monitor"
+	"monitorMultiview/internal/status"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// channelSummary is a channel as the main view lists it: its status and the
+// processes of the FFmpeg panel.
+type channelSummary struct {
+	status.Channel
+	Path      string                   `json:"path"`
+	Processes []*monitor.FFmpegProcess `json:"processes"`
+}
+
+type channelList struct {
+	Updated  time.Time        `json:"updated"`
+	BasePath string           `json:"base_path"`
+	State    string           `json:"state"`
+	Counts   map[string]int   `json:"counts"`
+	Channels []channelSummary `json:"channels"`
+}
+
+// channelDetail is everything the detail view shows about a channel.
+// Parsed playlists are left out of the package and served one at a time by
+// the playlist endpoint, which Playlists lists the names for.
+type channelDetail struct {
+	Updated   time.Time                `json:"updated"`
+	Channel   config.Channel           `json:"channel"`
+	Status    status.Channel           `json:"status"`
+	Processes []*monitor.FFmpegProcess `json:"processes"`
+	History   *monitor.ProcessHistory  `json:"history"`
+	Package   *monitor.HLSPackage      `json:"package"`
+	Playlists []string                 `json:"playlists"`
+}
+
+type playlistDetail struct {
+	Channel  string            `json:"channel"`
+	Name     string            `json:"name"`
+	Playlist *monitor.M3U8Info `json:"playlist"`
+}
+
+type processList struct {
+	Updated   time.Time                `json:"updated"`
+	Processes []*monitor.FFmpegProcess `json:"processes"`
+}
+
+// API serves the latest snapshot of collector as read-only JSON under
+// /api/v1/. Every response carries an ETag of its body, so pollers sending
+// If-None-Match get 304 Not Modified while nothing changed.
+func API(collector *monitor.Collector) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/v1/channels", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, r, http.StatusOK, listChannels(collector, collector.Snapshot()))
+	})
+
+	mux.HandleFunc("GET /api/v1/channels/{id}", func(w http.ResponseWriter, r *http.Request) {
+		snapshot := collector.Snapshot()
+		ch, ok := snapshot.Channel(r.PathValue("id"))
+		if !ok {
+			writeError(w, r, http.StatusNotFound, "unknown channel: "+r.PathValue("id"))
+			return
+		}
+		detail := channelDetail{
+			Updated:   snapshot.Updated(),
+			Channel:   ch,
+			Status:    channelStatus(snapshot, ch.ID),
+			Processes: processes(snapshot, ch.ID),
+			History:   snapshot.History(ch.ID),
+			Playlists: []string{},
+		}
+		if pkg := snapshot.Package(ch.ID); pkg != nil {
+			// Snapshots are shared, so strip the playlists from a copy
+			stripped := *pkg
+			stripped.Playlists = nil
+			detail.Package = &stripped
+			for name := range pkg.Playlists {
+				detail.Playlists = append(detail.Playlists, name)
+			}
+			sort.Strings(detail.Playlists)
+		}
+		writeJSON(w, r, http.StatusOK, detail)
+	})
+
+	mux.HandleFunc("GET /api/v1/channels/{id}/playlist", func(w http.ResponseWriter, r *http.Request) {
+		snapshot := collector.Snapshot()
+		id := r.PathValue("id")
+		if _, ok := snapshot.Channel(id); !ok {
+			writeError(w, r, http.StatusNotFound, "unknown channel: "+id)
+			return
+		}
+		pkg := snapshot.Package(id)
+		if pkg == nil {
+			writeError(w, r, http.StatusNotFound, "no HLS package for channel "+id)
+			return
+		}
+		// ?name= selects another playlist of the package
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			name = pkg.MainPlaylist()
+		}
+		if name == "" {
+			writeError(w, r, http.StatusNotFound, "no playlist for channel "+id)
+			return
+		}
+		info := pkg.Playlists[name]
+		if info == nil {
+			writeError(w, r, http.StatusNotFound, "no parsed playlist "+name+" for channel "+id)
+			return
+		}
+		writeJSON(w, r, http.StatusOK, playlistDetail{Channel: id, Name: name, Playlist: info})
+	})
+
+	mux.HandleFunc("GET /api/v1/processes", func(w http.ResponseWriter, r *http.Request) {
+		snapshot := collector.Snapshot()
+		list := processList{
+			Updated:   snapshot.ProcessesTaken,
+			Processes: []*monitor.FFmpegProcess{},
+		}
+		for _, ch := range snapshot.Channels {
+			list.Processes = append(list.Processes, snapshot.ChannelProcesses(ch.ID)...)
+		}
+		writeJSON(w, r, http.StatusOK, list)
+	})
+
+	mux.Handle("GET /api/v1/events", events(collector))
+
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		if !readOnly(w, r) {
+			return
+		}
+		writeError(w, r, http.StatusNotFound, "unknown endpoint: "+r.URL.Path)
+	})
+	return mux
+}
+
+func listChannels(collector *monitor.Collector, snapshot *monitor.Snapshot) channelList {
+	report := status.Build(snapshot)
+	list := channelList{
+		Updated:  snapshot.Updated(),
+		BasePath: collector.Config().HLS.BasePath,
+		State:    report.State,
+		Counts:   report.Counts,
+		Channels: make([]channelSummary, 0, len(report.Channels)),
+	}
+	for i, ch := range snapshot.Channels {
+		list.Channels = append(list.Channels, channelSummary{
+			Channel:   report.Channels[i],
+			Path:      ch.Path,
+			Processes: processes(snapshot, ch.ID),
+		})
+	}
+	return list
+}
+
+// processes returns the processes of a channel, never nil so it encodes as
+// an empty list.
+func processes(snapshot *monitor.Snapshot, channelID string) []*monitor.FFmpegProcess {
+	if procs := snapshot.ChannelProcesses(channelID); procs != nil {
+		return procs
+	}
+	return []*monitor.FFmpegProcess{}
+}
+
+func channelStatus(snapshot *monitor.Snapshot, channelID string) status.Channel {
+	for _, ch := range status.Build(snapshot).Channels {
+		if ch.ID == channelID {
+			return ch
+		}
+	}
+	return status.Channel{ID: channelID}
+}
+
+// writeJSON encodes v with an ETag of the body and answers 304 Not Modified
+// when the request already has it.
+func writeJSON(w http.ResponseWriter, r *http.Request, code int, v any) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(body.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	header := w.Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", "no-cache")
+	if code == http.StatusOK && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	header.Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if r.Method != http.MethodHead {
+		w.Write(body.Bytes())
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	writeJSON(w, r, code, map[string]string{"error": message})
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing
+// weakly as RFC 9110 asks for.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/json"
+	"monitorMultiview/internal/config"
+	"monitorMultiview/internal/monitor"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestCollector samples two channels once: ch01 with a playlist and ch02
+// without output, and no ffmpeg processes.
+func newTestCollector(t *testing.T) *monitor.Collector {
+	t.Helper()
+	base := t.TempDir()
+	dir := filepath.Join(base, "channel01")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	playlist := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:1\n#EXTINF:6.0,\nseg1.ts\n"
+	for name, content := range map[string]string{"index.m3u8": playlist, "seg1.ts": "ts"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.HLS.BasePath = base
+	cfg.HLS.WatchMode = "poll"
+	cfg.Channels.Count = 2
+
+	hlsMonitor := monitor.NewHLSMonitor(cfg)
+	t.Cleanup(func() { hlsMonitor.Close() })
+	collector := monitor.NewCollector(cfg, monitor.NewFFmpegMonitorWithProcRoot(cfg, t.TempDir()), hlsMonitor)
+	collector.CollectOnce()
+	return collector
+}
+
+func get(t *testing.T, handler http.Handler, method, target string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPINotModified(t *testing.T) {
+	api := API(newTestCollector(t))
+	for _, target := range []string{
+		"/api/v1/channels",
+		"/api/v1/channels/ch01",
+		"/api/v1/channels/ch01/playlist",
+		"/api/v1/processes",
+	} {
+		t.Run(target, func(t *testing.T) {
+			first := get(t, api, http.MethodGet, target, nil)
+			etag := first.Header().Get("ETag")
+			if first.Code != http.StatusOK || etag == "" {
+				t.Fatalf("GET: %d with ETag %q, want 200 with an ETag", first.Code, etag)
+			}
+
+			// Ages must not move the ETag while the snapshot stays the same
+			time.Sleep(20 * time.Millisecond)
+			second := get(t, api, http.MethodGet, target, http.Header{"If-None-Match": {etag}})
+			if second.Code != http.StatusNotModified {
+				t.Fatalf("GET with If-None-Match: %d, want 304", second.Code)
+			}
+			if second.Body.Len() != 0 {
+				t.Errorf("304 has a body of %d bytes", second.Body.Len())
+			}
+
+			other := get(t, api, http.MethodGet, target, http.Header{"If-None-Match": {`"other", W/` + etag}})
+			if other.Code != http.StatusNotModified {
+				t.Errorf("GET with a weak ETag in a list: %d, want 304", other.Code)
+			}
+			stale := get(t, api, http.MethodGet, target, http.Header{"If-None-Match": {`"other"`}})
+			if stale.Code != http.StatusOK {
+				t.Errorf("GET with another ETag: %d, want 200", stale.Code)
+			}
+		})
+	}
+}
+
+func TestAPIHead(t *testing.T) {
+	api := API(newTestCollector(t))
+	full := get(t, api, http.MethodGet, "/api/v1/channels", nil)
+	head := get(t, api, http.MethodHead, "/api/v1/channels", nil)
+	if head.Code != http.StatusOK {
+		t.Fatalf("HEAD: %d, want 200", head.Code)
+	}
+	if head.Body.Len() != 0 {
+		t.Errorf("HEAD has a body of %d bytes", head.Body.Len())
+	}
+	if head.Header().Get("ETag") != full.Header().Get("ETag") {
+		t.Errorf("HEAD ETag %q differs from GET ETag %q", head.Header().Get("ETag"), full.Header().Get("ETag"))
+	}
+	if ct := head.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("HEAD Content-Type %q", ct)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	api := API(newTestCollector(t))
+	tests := []struct {
+		method string
+		target string
+		code   int
+	}{
+		{http.MethodGet, "/api/v1/channels/nope", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/channels/nope/playlist", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/channels/ch02/playlist", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/channels/ch01/playlist?name=missing.m3u8", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/nope", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/channels", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/api/v1/channels/ch01", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rec := get(t, api, tt.method, tt.target, nil)
+		if rec.Code != tt.code {
+			t.Errorf("%s %s: %d, want %d", tt.method, tt.target, rec.Code, tt.code)
+			continue
+		}
+		if tt.code == http.StatusNotFound {
+			var body map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"] == "" {
+				t.Errorf("%s %s: body %q, want a JSON error", tt.method, tt.target, rec.Body.String())
+			}
+		}
+	}
+}
+
+func TestAPIChannels(t *testing.T) {
+	rec := get(t, API(newTestCollector(t)), http.MethodGet, "/api/v1/channels", nil)
+	var list channelList
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Channels) != 2 || list.Channels[0].ID != "ch01" || list.Channels[1].ID != "ch02" {
+		t.Fatalf("channels = %+v, want ch01 and ch02", list.Channels)
+	}
+	if ch := list.Channels[0]; ch.Health != monitor.HealthOK || ch.Segments != 1 || ch.Process != monitor.StatusStopped {
+		t.Errorf("ch01 = %+v, want an OK package with one segment and no process", ch)
+	}
+	if ch := list.Channels[1]; ch.Health != monitor.HealthMissing || ch.PlaylistAge != -1 {
+		t.Errorf("ch02 = %+v, want a missing package", ch)
+	}
+	if list.State != "CRITICAL" || list.Counts["CRITICAL"] != 2 {
+		t.Errorf("state %s, counts %v, want both channels critical", list.State, list.Counts)
+	}
+}
+
+func TestAPIProcesses(t *testing.T) {
+	rec := get(t, API(newTestCollector(t)), http.MethodGet, "/api/v1/processes", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET: %d", rec.Code)
+	}
+	var list struct {
+		Processes []json.RawMessage `json:"processes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if list.Processes == nil || len(list.Processes) != 0 {
+		t.Errorf("processes = %s, want an empty list", rec.Body.String())
+	}
+}
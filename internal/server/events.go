@@ -0,0 +1,158 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"monitorMultiview/internal/monitor"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// heartbeatInterval keeps idle streams from being closed by proxies.
+const heartbeatInterval = 15 * time.Second
+
+// channelKey holds what makes a channel worth an event when it changes.
+type channelKey struct {
+	State         string
+	Process       string
+	PID           int
+	Health        string
+	MediaSequence int
+	LatestFile    string
+}
+
+func (k channelKey) changes(old channelKey) []string {
+	var changes []string
+	if k.State != old.State {
+		changes = append(changes, "state")
+	}
+	if k.Process != old.Process {
+		changes = append(changes, "process")
+	}
+	if k.PID != old.PID {
+		changes = append(changes, "pid")
+	}
+	if k.Health != old.Health {
+		changes = append(changes, "hls_health")
+	}
+	if k.MediaSequence != old.MediaSequence {
+		changes = append(changes, "media_sequence")
+	}
+	if k.LatestFile != old.LatestFile {
+		changes = append(changes, "latest_file")
+	}
+	return changes
+}
+
+func keyOf(ch channelSummary) channelKey {
+	return channelKey{
+		State:         ch.State,
+		Process:       ch.Process,
+		PID:           ch.PID,
+		Health:        ch.Health,
+		MediaSequence: ch.MediaSequence,
+		LatestFile:    ch.LatestFile,
+	}
+}
+
+// channelEvent reports one channel whose key changed.
+type channelEvent struct {
+	ID      string         `json:"id"`
+	Changes []string       `json:"changes"`
+	Channel channelSummary `json:"channel"`
+}
+
+// stream writes Server-Sent Events with increasing IDs.
+type stream struct {
+	w    io.Writer
+	rc   *http.ResponseController
+	next int
+}
+
+func (s *stream) send(event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	s.next++
+	if _, err := fmt.Fprintf(s.w, "id: %d\nevent: %s\ndata: %s\n\n", s.next, event, payload); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// events streams channel changes as Server-Sent Events. A client first gets
+// a "snapshot" event with the full channel list, then a "channel" event
+// whenever the state, process, PID, HLS health, media sequence or newest
+// segment of a channel changes. When channels are added or removed the
+// full list is sent again.
+func events(collector *monitor.Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshots, cancel := collector.Subscribe()
+		defer cancel()
+
+		header := w.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		s := &stream{w: w, rc: http.NewResponseController(w)}
+		list := listChannels(collector, collector.Snapshot())
+		if err := s.send("snapshot", list); err != nil {
+			return
+		}
+		ids, keys := channelKeys(list)
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+					return
+				}
+				if err := s.rc.Flush(); err != nil {
+					return
+				}
+			case snapshot := <-snapshots:
+				list := listChannels(collector, snapshot)
+				newIDs, newKeys := channelKeys(list)
+				if !slices.Equal(ids, newIDs) {
+					if err := s.send("snapshot", list); err != nil {
+						return
+					}
+				} else {
+					for _, ch := range list.Channels {
+						changes := newKeys[ch.ID].changes(keys[ch.ID])
+						if len(changes) == 0 {
+							continue
+						}
+						if err := s.send("channel", channelEvent{ID: ch.ID, Changes: changes, Channel: ch}); err != nil {
+							return
+						}
+					}
+				}
+				ids, keys = newIDs, newKeys
+			}
+		}
+	})
+}
+
+func channelKeys(list channelList) ([]string, map[string]channelKey) {
+	ids := make([]string, 0, len(list.Channels))
+	keys := make(map[string]channelKey, len(list.Channels))
+	for _, ch := range list.Channels {
+		ids = append(ids, ch.ID)
+		keys[ch.ID] = keyOf(ch)
+	}
+	return ids, keys
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"monitorMultiview/internal/monitor"
+	"monitorMultiview/internal/status"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// metric is one metric family with a value per channel. value returns false
+// to leave out a channel, e.g. the playlist age while there is no playlist.
+type metric struct {
+	name  string
+	kind  string
+	help  string
+	value func(ch status.Channel) (float64, bool)
+}
+
+var channelMetrics = []metric{
+	{"ffmpeg_up", "gauge", "Whether at least one FFmpeg process serves the channel.",
+		func(ch status.Channel) (float64, bool) { return boolValue(ch.Processes > 0), true }},
+	{"ffmpeg_processes", "gauge", "Number of FFmpeg processes serving the channel.",
+		func(ch status.Channel) (float64, bool) { return float64(ch.Processes), true }},
+	{"ffmpeg_cpu_percent", "gauge", "CPU usage of the channel's FFmpeg processes in percent of one core.",
+		func(ch status.Channel) (float64, bool) { return ch.CPUPercent, true }},
+	{"ffmpeg_resident_memory_bytes", "gauge", "Resident memory of the channel's FFmpeg processes.",
+		func(ch status.Channel) (float64, bool) { return float64(ch.RSS), true }},
+	{"ffmpeg_restarts_total", "counter", "FFmpeg restarts seen since the monitor started.",
+		func(ch status.Channel) (float64, bool) { return float64(ch.Restarts), true }},
+	{"hls_playlist_age_seconds", "gauge", "Seconds since the channel's playlist was last modified.",
+		func(ch status.Channel) (float64, bool) { return ch.PlaylistAge, ch.PlaylistAge >= 0 }},
+	{"hls_segment_count", "gauge", "Number of segments in the channel's HLS directory.",
+		func(ch status.Channel) (float64, bool) { return float64(ch.Segments), true }},
+	{"hls_total_bytes", "gauge", "Total size of the channel's HLS directory.",
+		func(ch status.Channel) (float64, bool) { return float64(ch.TotalBytes), true }},
+	{"hls_media_sequence", "gauge", "Current #EXT-X-MEDIA-SEQUENCE of the channel's playlist.",
+		func(ch status.Channel) (float64, bool) { return float64(ch.MediaSequence), true }},
+	{"multiview_channel_health", "gauge", "Channel health: 0 OK, 1 WARNING, 2 CRITICAL.",
+		func(ch status.Channel) (float64, bool) { return float64(status.ExitCode(ch.State)), true }},
+}
+
+// Metrics serves the latest snapshot of collector in the Prometheus text
+// exposition format.
+func Metrics(collector *monitor.Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !readOnly(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if r.Method == http.MethodHead {
+			return
+		}
+		snapshot := collector.Snapshot()
+		writeMetrics(w, snapshot, status.Build(snapshot))
+	})
+}
+
+func writeMetrics(w io.Writer, snapshot *monitor.Snapshot, report *status.Report) {
+	b := bufio.NewWriter(w)
+	defer b.Flush()
+
+	for _, m := range channelMetrics {
+		header(b, m.name, m.kind, m.help)
+		for _, ch := range report.Channels {
+			if value, ok := m.value(ch); ok {
+				fmt.Fprintf(b, "%s{channel=\"%s\"} %s\n", m.name, escapeLabel(ch.ID), formatFloat(value))
+			}
+		}
+	}
+
+	header(b, "multiview_channels", "gauge", "Number of channels by health state.")
+	for _, state := range []string{status.StateOK, status.StateWarning, status.StateCritical} {
+		fmt.Fprintf(b, "multiview_channels{state=\"%s\"} %d\n", state, report.Counts[state])
+	}
+
+	if updated := snapshot.Updated(); !updated.IsZero() {
+		header(b, "multiview_last_sample_timestamp_seconds", "gauge", "Unix time of the newest sample.")
+		fmt.Fprintf(b, "multiview_last_sample_timestamp_seconds %s\n", formatFloat(float64(updated.UnixMilli())/1000))
+	}
+}
+
+func header(b *bufio.Writer, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
@@ -0,0 +1,80 @@
+// Package server publishes the collector's snapshots over HTTP.
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// shutdownTimeout bounds how long Close waits for open requests.
+const shutdownTimeout = 5 * time.Second
+
+// Server serves a handler on one listen address until Close.
+type Server struct {
+	http     *http.Server
+	listener net.Listener
+	done     chan struct{}
+	// cancel ends the context of every request, which long-lived streams
+	// wait on
+	cancel context.CancelFunc
+}
+
+// Start listens on addr right away, so a busy or invalid address is
+// reported to the caller, and serves handler in the background.
+func Start(addr string, handler http.Handler) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		http: &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+			BaseContext:       func(net.Listener) context.Context { return ctx },
+		},
+		listener: listener,
+		done:     make(chan struct{}),
+		cancel:   cancel,
+	}
+	go func() {
+		defer close(s.done)
+		if err := s.http.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("HTTP server on %s: %v", addr, err)
+		}
+	}()
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops accepting connections and waits briefly for open requests.
+func (s *Server) Close() error {
+	s.cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := s.http.Shutdown(ctx)
+	if err != nil {
+		s.http.Close()
+	}
+	<-s.done
+	return err
+}
+
+// readOnly rejects every method but GET and HEAD.
+func readOnly(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
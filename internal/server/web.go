@@ -0,0 +1,33 @@
+package server
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// indexHTML is the whole dashboard, with its styles and script inline so it
+// works without any other asset.
+//
+//go:embed web/index.html
+var indexHTML []byte
+
+// Web serves the dashboard page at /. It reads the JSON API and the event
+// stream, which must be served under /api/ on the same address.
+func Web() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !readOnly(w, r) {
+			return
+		}
+		if r.URL.Path != "/" && r.URL.Path != "/index.html" {
+			http.NotFound(w, r)
+			return
+		}
+		header := w.Header()
+		header.Set("Content-Type", "text/html; charset=utf-8")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Content-Security-Policy", "default-src 'self'; style-src 'unsafe-inline'; script-src 'unsafe-inline'")
+		if r.Method != http.MethodHead {
+			w.Write(indexHTML)
+		}
+	})
+}
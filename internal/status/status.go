@@ -0,0 +1,296 @@
+// Package status condenses a collector snapshot into a per-channel health
+// report for scripts and monitoring systems.
+package status
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"monitorMultiview/internal/config"
+	"monitorMultiview/internal/monitor"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Report states, worst last, as used by Nagios plugins.
+const (
+	StateOK       = "OK"
+	StateWarning  = "WARNING"
+	StateCritical = "CRITICAL"
+)
+
+// ExitUnknown is the Nagios exit code when no report could be made, e.g.
+// because the configuration is invalid.
+const ExitUnknown = 3
+
+// ExitCode returns the Nagios plugin exit code of a report state.
+func ExitCode(state string) int {
+	switch state {
+	case StateOK:
+		return 0
+	case StateWarning:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func stateRank(state string) int {
+	return ExitCode(state)
+}
+
+// Output formats accepted by Write.
+var Formats = []string{"table", "json", "yaml", "csv"}
+
+// ValidFormat reports whether Write supports format.
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Channel is the process and HLS health of one channel.
+type Channel struct {
+	ID   string `json:"id" yaml:"id"`
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	Port int    `json:"port,omitempty" yaml:"port,omitempty"`
+
+	// Process is the status of the primary process, or STOP/GONE
+	Process    string  `json:"process" yaml:"process"`
+	PID        int     `json:"pid,omitempty" yaml:"pid,omitempty"`
+	Processes  int     `json:"processes" yaml:"processes"`
+	CPUPercent float64 `json:"cpu_percent" yaml:"cpu_percent"`
+	RSS        int64   `json:"rss_bytes" yaml:"rss_bytes"`
+	Restarts   int     `json:"restarts" yaml:"restarts"`
+
+	Health       string `json:"hls_health" yaml:"hls_health"`
+	HealthReason string `json:"hls_reason,omitempty" yaml:"hls_reason,omitempty"`
+	// PlaylistAge is in seconds as of the HLS sample, -1 without a playlist
+	PlaylistAge   float64 `json:"playlist_age_seconds" yaml:"playlist_age_seconds"`
+	Segments      int     `json:"segments" yaml:"segments"`
+	TotalBytes    int64   `json:"total_bytes" yaml:"total_bytes"`
+	MediaSequence int     `json:"media_sequence" yaml:"media_sequence"`
+	LatestFile    string  `json:"latest_file,omitempty" yaml:"latest_file,omitempty"`
+
+	State  string `json:"state" yaml:"state"`
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// Report is the health of every channel at one point in time.
+type Report struct {
+	Generated time.Time      `json:"generated" yaml:"generated"`
+	State     string         `json:"state" yaml:"state"`
+	Counts    map[string]int `json:"counts" yaml:"counts"`
+	Channels  []Channel      `json:"channels" yaml:"channels"`
+}
+
+// ExitCode returns the Nagios plugin exit code of the overall state.
+func (r *Report) ExitCode() int {
+	return ExitCode(r.State)
+}
+
+// Build derives the report from a snapshot. The worst channel decides the
+// overall state.
+func Build(snapshot *monitor.Snapshot) *Report {
+	report := &Report{
+		Generated: snapshot.Updated(),
+		State:     StateOK,
+		Counts:    map[string]int{StateOK: 0, StateWarning: 0, StateCritical: 0},
+		Channels:  make([]Channel, 0, len(snapshot.Channels)),
+	}
+	for _, ch := range snapshot.Channels {
+		channel := buildChannel(snapshot, ch)
+		report.Counts[channel.State]++
+		if stateRank(channel.State) > stateRank(report.State) {
+			report.State = channel.State
+		}
+		report.Channels = append(report.Channels, channel)
+	}
+	return report
+}
+
+func buildChannel(snapshot *monitor.Snapshot, ch config.Channel) Channel {
+	channel := Channel{
+		ID:          ch.ID,
+		Name:        ch.Name,
+		Port:        ch.Port,
+		Health:      monitor.HealthMissing,
+		PlaylistAge: -1,
+	}
+
+	if history := snapshot.History(ch.ID); history != nil {
+		channel.Restarts = history.RestartCount
+	}
+	procs := snapshot.ChannelProcesses(ch.ID)
+	channel.Processes = len(procs)
+	if len(procs) > 0 {
+		primary := procs[0]
+		channel.Process = primary.Status
+		channel.PID = primary.PID
+		channel.Port = primary.Port
+		for _, proc := range procs {
+			channel.CPUPercent += proc.CPUPercent
+			channel.RSS += proc.RSS
+		}
+	} else if ch.Vanished {
+		channel.Process = monitor.StatusGone
+	} else {
+		channel.Process = monitor.StatusStopped
+	}
+
+	if pkg := snapshot.Package(ch.ID); pkg != nil {
+		channel.Health = pkg.Health
+		channel.HealthReason = pkg.HealthReason
+		if !pkg.PlaylistModTime.IsZero() {
+			// Measured at the sample, so one snapshot always gives the same
+			// report
+			taken := snapshot.PackagesTaken
+			if taken.IsZero() {
+				taken = time.Now()
+			}
+			channel.PlaylistAge = max(taken.Sub(pkg.PlaylistModTime).Seconds(), 0)
+		}
+		channel.Segments = pkg.SegmentCount
+		channel.TotalBytes = pkg.TotalSize
+		channel.LatestFile = pkg.LatestFile
+		if len(pkg.Sequences) > 0 {
+			channel.MediaSequence = pkg.Sequences[0].MediaSequence
+		}
+	}
+
+	channel.State, channel.Reason = classify(channel)
+	return channel
+}
+
+// classify maps process and HLS health to a report state. A channel that
+// stopped or whose output is stale or missing is critical; flapping,
+// ambiguous matches and late playlists are warnings.
+func classify(ch Channel) (string, string) {
+	process := StateOK
+	switch ch.Process {
+	case monitor.StatusStopped, monitor.StatusZombie:
+		process = StateCritical
+	case monitor.StatusFlapping, monitor.StatusAmbiguous, monitor.StatusGone:
+		process = StateWarning
+	}
+
+	hls := StateOK
+	switch ch.Health {
+	case monitor.HealthWarn:
+		hls = StateWarning
+	case monitor.HealthStale, monitor.HealthMissing:
+		hls = StateCritical
+	}
+
+	state := process
+	if stateRank(hls) > stateRank(state) {
+		state = hls
+	}
+
+	var reason string
+	if process != StateOK {
+		reason = "process " + ch.Process
+	}
+	if hls != StateOK {
+		if reason != "" {
+			reason += ", "
+		}
+		reason += "hls " + ch.Health
+		if ch.HealthReason != "" {
+			reason += ": " + ch.HealthReason
+		}
+	}
+	return state, reason
+}
+
+// Write renders the report as table, json, yaml or csv.
+func Write(w io.Writer, report *Report, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "csv":
+		return writeCSV(w, report)
+	case "table":
+		return writeTable(w, report)
+	default:
+		return fmt.Errorf("unknown output format: %q (must be table, json, yaml or csv)", format)
+	}
+}
+
+var columns = []string{"ID", "PROCESS", "PID", "PORT", "CPU%", "RSS", "RESTARTS", "HLS", "AGE", "SEGMENTS", "SIZE", "SEQUENCE", "STATE", "REASON"}
+
+func writeCSV(w io.Writer, report *Report) error {
+	cw := csv.NewWriter(w)
+	header := []string{"id", "process", "pid", "port", "cpu_percent", "rss_bytes", "restarts", "hls_health",
+		"playlist_age_seconds", "segments", "total_bytes", "media_sequence", "state", "reason"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, ch := range report.Channels {
+		cw.Write([]string{
+			ch.ID,
+			ch.Process,
+			strconv.Itoa(ch.PID),
+			strconv.Itoa(ch.Port),
+			strconv.FormatFloat(ch.CPUPercent, 'f', 1, 64),
+			strconv.FormatInt(ch.RSS, 10),
+			strconv.Itoa(ch.Restarts),
+			ch.Health,
+			strconv.FormatFloat(ch.PlaylistAge, 'f', 1, 64),
+			strconv.Itoa(ch.Segments),
+			strconv.FormatInt(ch.TotalBytes, 10),
+			strconv.Itoa(ch.MediaSequence),
+			ch.State,
+			ch.Reason,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeTable(w io.Writer, report *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(columns, "\t"))
+
+	for _, ch := range report.Channels {
+		pid, cpu, rss := "-", "-", "-"
+		if ch.Processes > 0 {
+			pid = strconv.Itoa(ch.PID)
+			cpu = fmt.Sprintf("%.1f", ch.CPUPercent)
+			rss = monitor.FormatFileSize(ch.RSS)
+		}
+		age := "-"
+		if ch.PlaylistAge >= 0 {
+			age = monitor.FormatDuration(time.Duration(ch.PlaylistAge * float64(time.Second)))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t:%d\t%s\t%s\t%d\t%s\t%s\t%d\t%s\t%d\t%s\t%s\n",
+			ch.ID, ch.Process, pid, ch.Port, cpu, rss, ch.Restarts,
+			ch.Health, age, ch.Segments, monitor.FormatFileSize(ch.TotalBytes), ch.MediaSequence,
+			ch.State, ch.Reason)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%s - %d channels: %d ok, %d warning, %d critical\n",
+		report.State, len(report.Channels),
+		report.Counts[StateOK], report.Counts[StateWarning], report.Counts[StateCritical])
+	return err
+}
@@ -2,8 +2,8 @@ package ui
 
 import (
 	"fmt"
+	"monitorMultiview/internal/config"
 	"monitorMultiview/internal/monitor"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -15,28 +15,41 @@ import (
 type DetailViewModel struct {
 	channelID      string
 	viewport       viewport.Model
-	ffmpegMonitor  *monitor.FFmpegMonitor
-	hlsMonitor     *monitor.HLSMonitor
+	cfg            *config.Config
+	collector      *monitor.Collector
 	lastUpdate     time.Time
+	paused         bool
 	width          int
 	height         int
 	ready          bool
 }
 
-func NewDetailViewModel(channelID string, ffmpegMonitor *monitor.FFmpegMonitor, hlsMonitor *monitor.HLSMonitor) *DetailViewModel {
+func NewDetailViewModel(channelID string, cfg *config.Config, collector *monitor.Collector) *DetailViewModel {
 	return &DetailViewModel{
-		channelID:     channelID,
-		ffmpegMonitor: ffmpegMonitor,
-		hlsMonitor:    hlsMonitor,
-		lastUpdate:    time.Now(),
+		channelID: channelID,
+		cfg:       cfg,
+		collector: collector,
 	}
 }
 
 func (m *DetailViewModel) Init() tea.Cmd {
-	return tea.Batch(
-		m.updateDetailData(),
-		tickCmd(),
-	)
+	if !m.paused {
+		m.updateDetailData()
+	}
+	return tickCmd(m.cfg)
+}
+
+// SetConfig switches to a reloaded configuration.
+func (m *DetailViewModel) SetConfig(cfg *config.Config) {
+	m.cfg = cfg
+}
+
+// SetPaused stops or resumes updating the view from new snapshots.
+func (m *DetailViewModel) SetPaused(paused bool) {
+	m.paused = paused
+	if !paused {
+		m.updateDetailData()
+	}
 }
 
 func (m *DetailViewModel) Update(msg tea.Msg) (*DetailViewModel, tea.Cmd) {
@@ -52,6 +65,7 @@ func (m *DetailViewModel) Update(msg tea.Msg) (*DetailViewModel, tea.Cmd) {
 			m.viewport = viewport.New(msg.Width-4, msg.Height-8)
 			m.viewport.YPosition = 3
 			m.ready = true
+			m.updateDetailData()
 		} else {
 			m.viewport.Width = msg.Width - 4
 			m.viewport.Height = msg.Height - 8
@@ -69,7 +83,10 @@ func (m *DetailViewModel) Update(msg tea.Msg) (*DetailViewModel, tea.Cmd) {
 		}
 
 	case tickMsg:
-		cmds = append(cmds, m.updateDetailData(), tickCmd())
+		if !m.paused {
+			m.updateDetailData()
+		}
+		cmds = append(cmds, tickCmd(m.cfg))
 	}
 
 	return m, tea.Batch(cmds...)
@@ -84,8 +101,9 @@ func (m *DetailViewModel) View() string {
 	
 	helpBar := HelpStyle.Render(
 		fmt.Sprintf(
-			"Updated: %s  [Esc] Back to List  [↑↓] Scroll  [PgUp/PgDn] Page",
-			m.lastUpdate.Format("15:04:05"),
+			"Updated: %s%s  [Esc] Back to List  [↑↓] Scroll  [PgUp/PgDn] Page  [p] Pause",
+			formatUpdated(m.lastUpdate),
+			pausedMarker(m.paused),
 		),
 	)
 
@@ -97,117 +115,356 @@ func (m *DetailViewModel) View() string {
 	)
 }
 
-func (m *DetailViewModel) updateDetailData() tea.Cmd {
-	return func() tea.Msg {
-		var content strings.Builder
-		
-		// FFmpeg Process Information
-		processes := m.ffmpegMonitor.GetProcesses()
-		var process *monitor.FFmpegProcess
-		for _, proc := range processes {
-			if proc.ChannelID == m.channelID {
-				process = proc
-				break
-			}
+// updateDetailData renders the channel from the collector's latest snapshot.
+func (m *DetailViewModel) updateDetailData() {
+	snapshot := m.collector.Snapshot()
+	var content strings.Builder
+
+	if ch, ok := snapshot.Channel(m.channelID); ok {
+		writeChannelConfig(&content, ch)
+		content.WriteString("\n")
+	}
+	
+	// FFmpeg Process Information
+	processes := snapshot.ChannelProcesses(m.channelID)
+
+	content.WriteString(HeaderStyle.Render(fmt.Sprintf("FFmpeg Process Information (%d)", len(processes))))
+	content.WriteString("\n\n")
+	
+	if len(processes) == 0 {
+		content.WriteString(StatusStoppedStyle.Render("Process not running"))
+	}
+
+	for i, process := range processes {
+		if i > 0 {
+			content.WriteString("\n")
+		}
+		if len(processes) > 1 {
+			content.WriteString(fmt.Sprintf("── Process %d/%d (%s) ──\n", i+1, len(processes), process.Role))
+		}
+		content.WriteString(fmt.Sprintf("Channel ID: %s\n", process.ChannelID))
+		content.WriteString(fmt.Sprintf("Role: %s\n", process.Role))
+		content.WriteString(fmt.Sprintf("Port: %d\n", process.Port))
+		content.WriteString(fmt.Sprintf("PID: %d\n", process.PID))
+		content.WriteString(fmt.Sprintf("Status: %s\n", GetStatusColor(process.Status).Render(process.Status)))
+		content.WriteString(fmt.Sprintf("State: %s  CPU: %.1f%%  RSS: %s  Threads: %d  FDs: %s\n",
+			process.State, process.CPUPercent, monitor.FormatFileSize(process.RSS),
+			process.Threads, formatFDCount(process.FDCount)))
+		if !process.StartTime.IsZero() {
+			content.WriteString(fmt.Sprintf("Started: %s (up %s)\n",
+				process.StartTime.Format("2006-01-02 15:04:05"), monitor.FormatDuration(process.Uptime())))
+		}
+		if len(process.Candidates) > 1 {
+			content.WriteString(fmt.Sprintf("Ambiguous Match: %s\n", strings.Join(process.Candidates, ", ")))
+		}
+		content.WriteString(fmt.Sprintf("Command: %s\n", process.Command))
+		content.WriteString(fmt.Sprintf("Last Seen: %s\n", process.LastSeen.Format("2006-01-02 15:04:05")))
+		if process.Job != nil {
+			writeFFmpegJob(&content, process.Job)
 		}
+	}
 
-		content.WriteString(HeaderStyle.Render("FFmpeg Process Information"))
+	if history := snapshot.History(m.channelID); history != nil {
 		content.WriteString("\n\n")
+		writeProcessHistory(&content, history)
+	}
+
+	content.WriteString("\n\n")
+
+	// HLS Package Information
+	pkg := snapshot.Package(m.channelID)
+	
+	content.WriteString(HeaderStyle.Render("HLS Package Information"))
+	content.WriteString("\n\n")
+	
+	if pkg != nil {
+		content.WriteString(fmt.Sprintf("Path: %s\n", pkg.Path))
+		content.WriteString(fmt.Sprintf("Health: %s\n", GetHealthColor(pkg.Health).Render(pkg.Health)))
+		if pkg.HealthReason != "" {
+			content.WriteString(fmt.Sprintf("Reason: %s\n", pkg.HealthReason))
+		}
+		if !pkg.PlaylistModTime.IsZero() {
+			content.WriteString(fmt.Sprintf("Playlist Age: %s (target duration %ds)\n",
+				monitor.FormatDuration(pkg.PlaylistAge()), pkg.TargetDuration))
+		}
+		content.WriteString(fmt.Sprintf("Latest File: %s\n", pkg.LatestFile))
+		content.WriteString(fmt.Sprintf("Total Segments: %d\n", pkg.SegmentCount))
+		content.WriteString(fmt.Sprintf("Total Size: %s\n", monitor.FormatFileSize(pkg.TotalSize)))
+		content.WriteString(fmt.Sprintf("Last Update: %s\n", pkg.LastUpdate.Format("2006-01-02 15:04:05")))
 		
-		if process != nil {
-			content.WriteString(fmt.Sprintf("Channel ID: %s\n", process.ChannelID))
-			content.WriteString(fmt.Sprintf("Port: %d\n", process.Port))
-			content.WriteString(fmt.Sprintf("PID: %d\n", process.PID))
-			content.WriteString(fmt.Sprintf("Status: %s\n", GetStatusColor(process.Status).Render(process.Status)))
-			content.WriteString(fmt.Sprintf("Command: %s\n", process.Command))
-			content.WriteString(fmt.Sprintf("Last Seen: %s\n", process.LastSeen.Format("2006-01-02 15:04:05")))
+		if len(pkg.Sequences) > 0 {
+			content.WriteString("\nMedia Sequence Tracking:\n")
+			for _, seq := range pkg.Sequences {
+				content.WriteString(fmt.Sprintf("  %s  seq=%d  %s  rewinds=%d jumps=%d\n",
+					seq.Playlist, seq.MediaSequence, seq.Status, seq.Rewinds, seq.Jumps))
+				if seq.Detail != "" {
+					content.WriteString(fmt.Sprintf("    %s\n", seq.Detail))
+				}
+			}
+		}
+
+		if len(pkg.Findings) > 0 {
+			content.WriteString(fmt.Sprintf("\nConformance Findings (%d):\n", len(pkg.Findings)))
+			for _, finding := range pkg.Findings {
+				content.WriteString("  " + GetSeverityColor(finding.Severity).Render(finding.String()) + "\n")
+			}
 		} else {
-			content.WriteString(StatusStoppedStyle.Render("Process not running"))
+			content.WriteString("\nConformance Findings: none\n")
 		}
 
-		content.WriteString("\n\n")
+		content.WriteString(fmt.Sprintf("\nM3U8 Files (%d):\n", len(pkg.M3U8Files)))
+		for i, m3u8File := range pkg.M3U8Files {
+			content.WriteString(fmt.Sprintf("  %d. %s\n", i+1, m3u8File))
+		}
 
-		// HLS Package Information
-		pkg := m.hlsMonitor.GetPackageByChannel(m.channelID)
-		
-		content.WriteString(HeaderStyle.Render("HLS Package Information"))
-		content.WriteString("\n\n")
-		
-		if pkg != nil {
-			content.WriteString(fmt.Sprintf("Path: %s\n", pkg.Path))
-			content.WriteString(fmt.Sprintf("Latest File: %s\n", pkg.LatestFile))
-			content.WriteString(fmt.Sprintf("Total Segments: %d\n", pkg.SegmentCount))
-			content.WriteString(fmt.Sprintf("Total Size: %s\n", monitor.FormatFileSize(pkg.TotalSize)))
-			content.WriteString(fmt.Sprintf("Last Update: %s\n", pkg.LastUpdate.Format("2006-01-02 15:04:05")))
-			
-			content.WriteString(fmt.Sprintf("\nM3U8 Files (%d):\n", len(pkg.M3U8Files)))
-			for i, m3u8File := range pkg.M3U8Files {
-				content.WriteString(fmt.Sprintf("  %d. %s\n", i+1, m3u8File))
-			}
+		if len(pkg.Renditions) > 0 {
+			content.WriteString("\n")
+			content.WriteString(HeaderStyle.Render("Rendition Tree"))
+			content.WriteString("\n\n")
+			writeRenditionTree(&content, pkg)
+		}
 
-			// Try to parse and display M3U8 content
-			if len(pkg.M3U8Files) > 0 {
-				m3u8Path := filepath.Join(pkg.Path, pkg.M3U8Files[0])
-				m3u8Info, err := monitor.ParseM3U8(m3u8Path)
-				if err == nil {
-					content.WriteString("\n")
-					content.WriteString(HeaderStyle.Render("M3U8 Content Details"))
-					content.WriteString("\n\n")
-					content.WriteString(fmt.Sprintf("Version: %d\n", m3u8Info.Version))
-					content.WriteString(fmt.Sprintf("Target Duration: %d seconds\n", m3u8Info.TargetDuration))
-					content.WriteString(fmt.Sprintf("Media Sequence: %d\n", m3u8Info.MediaSequence))
-					content.WriteString(fmt.Sprintf("Total Segments in Playlist: %d\n", len(m3u8Info.Segments)))
-
-					content.WriteString("\nLatest Segments:\n")
-					segmentCount := len(m3u8Info.Segments)
-					start := segmentCount - 10
-					if start < 0 {
-						start = 0
-					}
-					
-					for i := start; i < segmentCount; i++ {
-						segment := m3u8Info.Segments[i]
-						marker := " "
-						if i == segmentCount-1 {
-							marker = "→"
+		// Try to parse and display M3U8 content, starting from the master
+		if playlist := pkg.MainPlaylist(); playlist != "" {
+			if m3u8Info := pkg.Playlists[playlist]; m3u8Info != nil {
+				content.WriteString("\n")
+				content.WriteString(HeaderStyle.Render("M3U8 Content Details"))
+				content.WriteString("\n\n")
+				writePlaylistDetails(&content, m3u8Info)
+				content.WriteString("\n")
+				content.WriteString(HeaderStyle.Render("M3U8 File Content"))
+				content.WriteString("\n\n")
+				
+				// Show M3U8 content with syntax highlighting
+				lines := strings.Split(m3u8Info.Content, "\n")
+				for _, line := range lines {
+					if strings.HasPrefix(line, "#") {
+						if strings.HasPrefix(line, "#EXTM3U") {
+							content.WriteString(lipgloss.NewStyle().Foreground(primaryColor).Render(line))
+						} else if strings.HasPrefix(line, "#EXT") {
+							content.WriteString(lipgloss.NewStyle().Foreground(secondaryColor).Render(line))
+						} else {
+							content.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render(line))
 						}
-						content.WriteString(fmt.Sprintf("  %s %s (%.1fs)\n", 
-							marker, segment.URI, segment.Duration))
+					} else if line != "" {
+						content.WriteString(line)
 					}
-
 					content.WriteString("\n")
-					content.WriteString(HeaderStyle.Render("M3U8 File Content"))
-					content.WriteString("\n\n")
-					
-					// Show M3U8 content with syntax highlighting
-					lines := strings.Split(m3u8Info.Content, "\n")
-					for _, line := range lines {
-						if strings.HasPrefix(line, "#") {
-							if strings.HasPrefix(line, "#EXTM3U") {
-								content.WriteString(lipgloss.NewStyle().Foreground(primaryColor).Render(line))
-							} else if strings.HasPrefix(line, "#EXT") {
-								content.WriteString(lipgloss.NewStyle().Foreground(secondaryColor).Render(line))
-							} else {
-								content.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render(line))
-							}
-						} else if line != "" {
-							content.WriteString(line)
-						}
-						content.WriteString("\n")
-					}
-				} else {
-					content.WriteString(fmt.Sprintf("\nError parsing M3U8: %v\n", err))
 				}
+			} else {
+				content.WriteString(fmt.Sprintf("\nCould not read %s\n", playlist))
 			}
-		} else {
-			content.WriteString(StatusStoppedStyle.Render("No HLS package found"))
 		}
+	} else {
+		content.WriteString(StatusStoppedStyle.Render("No HLS package found"))
+	}
 
-		m.viewport.SetContent(content.String())
-		m.lastUpdate = time.Now()
-		
-		return nil
+	m.viewport.SetContent(content.String())
+	m.lastUpdate = snapshot.Updated()
+}
+
+func writeChannelConfig(content *strings.Builder, ch config.Channel) {
+	ports := make([]string, len(ch.Ports))
+	for i, port := range ch.Ports {
+		ports[i] = fmt.Sprintf("%d", port)
+	}
+
+	content.WriteString(fmt.Sprintf("Name: %s\n", ch.Name))
+	if ch.Discovered != "" {
+		content.WriteString(fmt.Sprintf("Discovered: %s\n", ch.Discovered))
+	}
+	if ch.Vanished {
+		content.WriteString(StatusStoppedStyle.Render(fmt.Sprintf("Vanished: %s", ch.VanishedAt.Format("2006-01-02 15:04:05"))))
+		content.WriteString("\n")
+	}
+	content.WriteString(fmt.Sprintf("Ports: %s\n", valueOrDash(strings.Join(ports, ", "))))
+	if len(ch.Tags) > 0 {
+		content.WriteString(fmt.Sprintf("Tags: %s\n", strings.Join(ch.Tags, ", ")))
+	}
+	if ch.MasterPlaylist != "" {
+		content.WriteString(fmt.Sprintf("Master Playlist: %s\n", ch.MasterPlaylist))
+	}
+	if len(ch.Renditions) > 0 {
+		content.WriteString(fmt.Sprintf("Expected Renditions: %s\n", strings.Join(ch.Renditions, ", ")))
+	}
+	content.WriteString(fmt.Sprintf("Health Factors: warn %gx, stale %gx target duration\n", ch.WarnFactor, ch.StaleFactor))
+}
+
+func writeFFmpegJob(content *strings.Builder, job *monitor.FFmpegJob) {
+	content.WriteString(fmt.Sprintf("\nInputs (%d):\n", len(job.Inputs)))
+	for i, input := range job.Inputs {
+		content.WriteString(fmt.Sprintf("  %d. %s\n", i+1, input.URL))
+		content.WriteString(fmt.Sprintf("     Protocol: %s", input.Protocol))
+		if input.Host != "" {
+			content.WriteString(fmt.Sprintf("  Host: %s", input.Host))
+		}
+		if input.Port > 0 {
+			content.WriteString(fmt.Sprintf("  Port: %d", input.Port))
+		}
+		if input.Format != "" {
+			content.WriteString(fmt.Sprintf("  Format: %s", input.Format))
+		}
+		content.WriteString("\n")
+	}
+
+	content.WriteString(fmt.Sprintf("\nOutputs (%d):\n", len(job.Outputs)))
+	for i, output := range job.Outputs {
+		content.WriteString(fmt.Sprintf("  %d. %s\n", i+1, output.URL))
+		content.WriteString(fmt.Sprintf("     Muxer: %s  Video: %s %s  Audio: %s %s  Preset: %s\n",
+			valueOrDash(output.Muxer),
+			valueOrDash(output.VideoCodec), output.VideoBitrate,
+			valueOrDash(output.AudioCodec), output.AudioBitrate,
+			valueOrDash(output.Preset)))
+		if hls := output.HLS; hls != nil {
+			content.WriteString(fmt.Sprintf("     HLS: time=%gs list_size=%d flags=%s\n",
+				hls.Time, hls.ListSize, valueOrDash(strings.Join(hls.Flags, "+"))))
+			if hls.SegmentFilename != "" {
+				content.WriteString(fmt.Sprintf("     Segments: %s\n", hls.SegmentFilename))
+			}
+		}
+	}
+}
+
+func writeRenditionTree(content *strings.Builder, pkg *monitor.HLSPackage) {
+	root := pkg.MasterPlaylist
+	if root == "" {
+		root = "(no master playlist)"
+	}
+	content.WriteString(root + "\n")
+
+	for i, rendition := range pkg.Renditions {
+		branch := "├─"
+		if i == len(pkg.Renditions)-1 {
+			branch = "└─"
+		}
+
+		label := rendition.URI
+		switch {
+		case rendition.Resolution != "":
+			label += fmt.Sprintf("  %s %d bps", rendition.Resolution, rendition.Bandwidth)
+		case rendition.Bandwidth > 0:
+			label += fmt.Sprintf("  %d bps", rendition.Bandwidth)
+		case rendition.Name != "":
+			label += "  " + rendition.Name
+		}
+
+		content.WriteString(fmt.Sprintf("%s %s [%s] %s\n", branch, label, rendition.Type,
+			GetHealthColor(rendition.Health).Render(rendition.Health)))
+
+		indent := "│  "
+		if i == len(pkg.Renditions)-1 {
+			indent = "   "
+		}
+		if !rendition.LastUpdate.IsZero() {
+			content.WriteString(fmt.Sprintf("%s segments=%d  latest=%s  updated %s ago\n", indent,
+				rendition.SegmentCount, valueOrDash(rendition.LatestSegment),
+				monitor.FormatDuration(time.Since(rendition.LastUpdate))))
+		}
+		if rendition.HealthReason != "" && rendition.Health != monitor.HealthOK {
+			content.WriteString(fmt.Sprintf("%s %s\n", indent, rendition.HealthReason))
+		}
+	}
+}
+
+func writePlaylistDetails(content *strings.Builder, info *monitor.M3U8Info) {
+	playlistKind := "Media"
+	if info.IsMaster {
+		playlistKind = "Master"
+	}
+	content.WriteString(fmt.Sprintf("Type: %s playlist\n", playlistKind))
+	content.WriteString(fmt.Sprintf("Version: %d\n", info.Version))
+
+	if info.IsMaster {
+		content.WriteString(fmt.Sprintf("\nVariants (%d):\n", len(info.Variants)))
+		for _, variant := range info.Variants {
+			content.WriteString(fmt.Sprintf("  %s  %d bps  %s  %s  %.3g fps\n",
+				variant.URI, variant.Bandwidth, valueOrDash(variant.Resolution),
+				valueOrDash(variant.Codecs), variant.FrameRate))
+		}
+		if len(info.Media) > 0 {
+			content.WriteString(fmt.Sprintf("\nMedia (%d):\n", len(info.Media)))
+			for _, media := range info.Media {
+				content.WriteString(fmt.Sprintf("  %s  group=%s  name=%s  %s\n",
+					media.Type, media.GroupID, media.Name, valueOrDash(media.URI)))
+			}
+		}
+	} else {
+		content.WriteString(fmt.Sprintf("Target Duration: %d seconds\n", info.TargetDuration))
+		content.WriteString(fmt.Sprintf("Media Sequence: %d\n", info.MediaSequence))
+		content.WriteString(fmt.Sprintf("Discontinuity Sequence: %d\n", info.DiscontinuitySequence))
+		content.WriteString(fmt.Sprintf("Playlist Type: %s  Ended: %t\n", valueOrDash(info.PlaylistType), info.EndList))
+		content.WriteString(fmt.Sprintf("Total Segments in Playlist: %d\n", len(info.Segments)))
+
+		content.WriteString("\nLatest Segments:\n")
+		segmentCount := len(info.Segments)
+		start := segmentCount - 10
+		if start < 0 {
+			start = 0
+		}
+
+		for i := start; i < segmentCount; i++ {
+			segment := info.Segments[i]
+			marker := " "
+			if i == segmentCount-1 {
+				marker = "→"
+			}
+			var flags []string
+			if segment.Discontinuity {
+				flags = append(flags, "discontinuity")
+			}
+			if segment.Key != nil {
+				flags = append(flags, "key="+segment.Key.Method)
+			}
+			if segment.ByteRange != nil {
+				flags = append(flags, fmt.Sprintf("bytes=%d", segment.ByteRange.Length))
+			}
+			if !segment.ProgramDateTime.IsZero() {
+				flags = append(flags, segment.ProgramDateTime.Format("15:04:05.000"))
+			}
+			line := fmt.Sprintf("  %s #%d %s (%.1fs)", marker, segment.Sequence, segment.URI, segment.Duration)
+			if len(flags) > 0 {
+				line += " [" + strings.Join(flags, ", ") + "]"
+			}
+			content.WriteString(line + "\n")
+		}
+	}
+
+	if len(info.Errors) > 0 {
+		content.WriteString(fmt.Sprintf("\nParse Errors (%d):\n", len(info.Errors)))
+		for _, parseErr := range info.Errors {
+			content.WriteString(StatusStoppedStyle.Render("  "+parseErr.Error()) + "\n")
+		}
+	}
+}
+
+func writeProcessHistory(content *strings.Builder, history *monitor.ProcessHistory) {
+	content.WriteString(HeaderStyle.Render("Process History"))
+	content.WriteString("\n\n")
+	content.WriteString(fmt.Sprintf("First Seen: %s\n", history.FirstSeen.Format("2006-01-02 15:04:05")))
+	content.WriteString(fmt.Sprintf("Restarts: %d  Disappearances: %d\n", history.RestartCount, history.Disappearances))
+	if !history.LastDown.IsZero() {
+		content.WriteString(fmt.Sprintf("Last Down: %s\n", history.LastDown.Format("2006-01-02 15:04:05")))
+	}
+	if history.Flapping {
+		content.WriteString(StatusStoppedStyle.Render("Flapping: restart threshold exceeded"))
+		content.WriteString("\n")
+	}
+
+	start := len(history.Restarts) - 10
+	if start < 0 {
+		start = 0
+	}
+	for i := len(history.Restarts) - 1; i >= start; i-- {
+		event := history.Restarts[i]
+		content.WriteString(fmt.Sprintf("  %s  PID %d → %d\n", event.Time.Format("15:04:05"), event.OldPID, event.NewPID))
+	}
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
 	}
+	return value
 }
 
 type SwitchToMainMsg struct{}
\ No newline at end of file
@@ -5,6 +5,7 @@ import (
 	"monitorMultiview/internal/config"
 	"monitorMultiview/internal/monitor"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/table"
@@ -17,23 +18,35 @@ type MainViewModel struct {
 	hlsTable       table.Model
 	selectedPanel  int // 0 = ffmpeg, 1 = hls
 	selectedRow    int
-	ffmpegMonitor  *monitor.FFmpegMonitor
-	hlsMonitor     *monitor.HLSMonitor
+	cfg            *config.Config
+	collector      *monitor.Collector
+	runningChannels int
+	channelCount   int
+	healthCounts   map[string]int
 	lastUpdate     time.Time
+	paused         bool
 	width          int
 	height         int
 }
 
 type tickMsg time.Time
 
-func NewMainViewModel() *MainViewModel {
+func NewMainViewModel(cfg *config.Config, collector *monitor.Collector) *MainViewModel {
 	// Create FFmpeg table with dynamic sizing
 	ffmpegColumns := []table.Column{
 		{Title: "Ch", Width: 5},
-		{Title: "Port", Width: 8},
-		{Title: "PID", Width: 8},
-		{Title: "Status", Width: 8},
-		{Title: "Command", Width: 40},
+		{Title: "Role", Width: 9},
+		{Title: "Port", Width: 7},
+		{Title: "PID", Width: 7},
+		{Title: "St", Width: 2},
+		{Title: "CPU%", Width: 6},
+		{Title: "RSS", Width: 9},
+		{Title: "Thr", Width: 4},
+		{Title: "FDs", Width: 4},
+		{Title: "Uptime", Width: 7},
+		{Title: "Rst", Width: 3},
+		{Title: "Status", Width: 6},
+		{Title: "Command", Width: 20},
 	}
 
 	ffmpegTable := table.New(
@@ -46,8 +59,9 @@ func NewMainViewModel() *MainViewModel {
 	hlsColumns := []table.Column{
 		{Title: "Ch", Width: 5},
 		{Title: "Path", Width: 25},
+		{Title: "Health", Width: 7},
+		{Title: "Age", Width: 7},
 		{Title: "Latest File", Width: 18},
-		{Title: "M3U8", Width: 12},
 		{Title: "Segs", Width: 6},
 		{Title: "Size", Width: 10},
 	}
@@ -75,17 +89,29 @@ func NewMainViewModel() *MainViewModel {
 		ffmpegTable:   ffmpegTable,
 		hlsTable:      hlsTable,
 		selectedPanel: 0,
-		ffmpegMonitor: monitor.NewFFmpegMonitor(),
-		hlsMonitor:    monitor.NewHLSMonitor(),
-		lastUpdate:    time.Now(),
+		cfg:           cfg,
+		collector:     collector,
 	}
 }
 
 func (m *MainViewModel) Init() tea.Cmd {
-	return tea.Batch(
-		m.updateData(),
-		tickCmd(),
-	)
+	if !m.paused {
+		m.updateData()
+	}
+	return tickCmd(m.cfg)
+}
+
+// SetConfig switches to a reloaded configuration.
+func (m *MainViewModel) SetConfig(cfg *config.Config) {
+	m.cfg = cfg
+}
+
+// SetPaused stops or resumes updating the tables from new snapshots.
+func (m *MainViewModel) SetPaused(paused bool) {
+	m.paused = paused
+	if !paused {
+		m.updateData()
+	}
 }
 
 func (m *MainViewModel) Update(msg tea.Msg) (*MainViewModel, tea.Cmd) {
@@ -132,7 +158,10 @@ func (m *MainViewModel) Update(msg tea.Msg) (*MainViewModel, tea.Cmd) {
 		}
 
 	case tickMsg:
-		cmds = append(cmds, m.updateData(), tickCmd())
+		if !m.paused {
+			m.updateData()
+		}
+		cmds = append(cmds, tickCmd(m.cfg))
 	}
 
 	return m, tea.Batch(cmds...)
@@ -145,39 +174,36 @@ func (m *MainViewModel) View() string {
 
 	title := TitleStyle.
 		Width(m.width).
-		Render(fmt.Sprintf("MultiView Monitor - %s (%d channels)", config.GlobalConfig.HLS.BasePath, config.GlobalConfig.Channels.Count))
+		Render(fmt.Sprintf("MultiView Monitor - %s (%d channels)", m.cfg.HLS.BasePath, m.channelCount))
 	
 	// Calculate widths for two-column layout that fills the screen
 	leftWidth := (m.width - 6) / 2  // Account for borders and padding
 	rightWidth := m.width - leftWidth - 6
 
-	// Update table widths dynamically
-	m.updateTableWidths(leftWidth, rightWidth)
-
 	// FFmpeg panel with full height
-	ffmpegTitle := HeaderStyle.Render(fmt.Sprintf("FFmpeg Processes (%d)", config.GlobalConfig.Channels.Count))
+	ffmpegTitle := HeaderStyle.Render(fmt.Sprintf("FFmpeg Processes (%d)", m.channelCount))
 	ffmpegPanel := BaseStyle.Copy().
 		Width(leftWidth).
 		Height(m.height - 6).  // Fill available height
 		Render(ffmpegTitle + "\n" + m.ffmpegTable.View())
 
 	// HLS panel with full height  
-	hlsTitle := HeaderStyle.Render(fmt.Sprintf("HLS Packages (%d)", config.GlobalConfig.Channels.Count))
+	hlsTitle := HeaderStyle.Render(fmt.Sprintf("HLS Packages (%d)", m.channelCount))
 	hlsPanel := BaseStyle.Copy().
 		Width(rightWidth).
 		Height(m.height - 6).  // Fill available height
 		Render(hlsTitle + "\n" + m.hlsTable.View())
 
 	// Status bar spanning full width
-	runningCount := len(m.ffmpegMonitor.GetProcesses())
-	totalPackages := len(m.hlsMonitor.GetPackages())
+	runningCount := m.runningChannels
 	statusBar := HelpStyle.
 		Width(m.width).
 		Render(fmt.Sprintf(
-			"Status: %d/%d Running  Packages: %d/%d  Updated: %s  [Tab] Switch  [↑↓] Select  [Enter] Details  [q] Quit",
-			runningCount, config.GlobalConfig.Channels.Count, 
-			totalPackages, config.GlobalConfig.Channels.Count, 
-			m.lastUpdate.Format("15:04:05"),
+			"Status: %d/%d Running  HLS: %s  Updated: %s%s  [Tab] Switch  [↑↓] Select  [Enter] Details  [p] Pause  [q] Quit",
+			runningCount, m.channelCount,
+			m.healthSummary(),
+			formatUpdated(m.lastUpdate),
+			pausedMarker(m.paused),
 		))
 
 	// Layout filling the entire screen
@@ -191,170 +217,181 @@ func (m *MainViewModel) View() string {
 	)
 }
 
-func (m *MainViewModel) updateData() tea.Cmd {
-	return func() tea.Msg {
-		// Update FFmpeg processes
-		processes := m.ffmpegMonitor.GetProcesses()
-		ffmpegRows := make([]table.Row, 0, 24)
+// healthSummary renders the per-status channel counts of the last update.
+func (m *MainViewModel) healthSummary() string {
+	var parts []string
+	for _, status := range []string{monitor.HealthOK, monitor.HealthWarn, monitor.HealthStale, monitor.HealthMissing} {
+		if count := m.healthCounts[status]; count > 0 {
+			parts = append(parts, GetHealthColor(status).Render(fmt.Sprintf("%d %s", count, status)))
+		}
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, " ")
+}
 
-		// Create a map for quick lookup
-		processMap := make(map[string]*monitor.FFmpegProcess)
-		for _, proc := range processes {
-			processMap[proc.ChannelID] = proc
+// updateData rebuilds the tables from the collector's latest snapshot. It
+// does no I/O and therefore runs directly on the UI goroutine.
+func (m *MainViewModel) updateData() {
+	snapshot := m.collector.Snapshot()
+	ffmpegRows := make([]table.Row, 0, 24)
+	runningChannels := 0
+
+	// Generate rows for all configured channels, one per process
+	channels := snapshot.Channels
+	for _, ch := range channels {
+		restarts := "0"
+		if history := snapshot.History(ch.ID); history != nil {
+			restarts = fmt.Sprintf("%d", history.RestartCount)
 		}
 
-		// Generate rows for all configured channels
-		channels := config.GetChannels()
-		for _, ch := range channels {
-			if proc, exists := processMap[ch.ID]; exists {
+		if procs := snapshot.ChannelProcesses(ch.ID); len(procs) > 0 {
+			runningChannels++
+			for _, proc := range procs {
 				ffmpegRows = append(ffmpegRows, table.Row{
 					ch.ID,
+					proc.Role,
 					fmt.Sprintf(":%d", proc.Port),
 					fmt.Sprintf("%d", proc.PID),
+					proc.State,
+					fmt.Sprintf("%.1f", proc.CPUPercent),
+					monitor.FormatFileSize(proc.RSS),
+					fmt.Sprintf("%d", proc.Threads),
+					formatFDCount(proc.FDCount),
+					monitor.FormatDuration(proc.Uptime()),
+					restarts,
 					proc.Status,
 					TruncateText(proc.Command, 40),
 				})
-			} else {
-				ffmpegRows = append(ffmpegRows, table.Row{
-					ch.ID,
-					fmt.Sprintf(":%d", ch.Port),
-					"-",
-					"STOP",
-					"Not running",
-				})
 			}
+		} else {
+			ffmpegRows = append(ffmpegRows, table.Row{
+				ch.ID,
+				"-",
+				fmt.Sprintf(":%d", ch.Port),
+				"-",
+				"-",
+				"-",
+				"-",
+				"-",
+				"-",
+				"-",
+				restarts,
+				stoppedStatus(ch),
+				"Not running",
+			})
 		}
+	}
 
-		m.ffmpegTable.SetRows(ffmpegRows)
-
-		// Update HLS packages
-		packages := m.hlsMonitor.GetPackages()
-		hlsRows := make([]table.Row, 0, 24)
+	m.ffmpegTable.SetRows(ffmpegRows)
+	m.runningChannels = runningChannels
+	if m.channelCount != len(channels) {
+		m.channelCount = len(channels)
+		m.updateTableSizes()
+	}
 
-		packageMap := make(map[string]*monitor.HLSPackage)
-		for _, pkg := range packages {
-			packageMap[pkg.ChannelID] = pkg
-		}
+	// Update HLS packages
+	hlsRows := make([]table.Row, 0, 24)
 
-		for _, ch := range channels {
-			if pkg, exists := packageMap[ch.ID]; exists {
-				m3u8Count := fmt.Sprintf("%d files", len(pkg.M3U8Files))
-				if len(pkg.M3U8Files) == 1 {
-					m3u8Count = pkg.M3U8Files[0]
-					if len(m3u8Count) > 12 {
-						m3u8Count = m3u8Count[:9] + "..."
-					}
-				}
-
-				hlsRows = append(hlsRows, table.Row{
-					ch.ID,
-					TruncateText(filepath.Base(pkg.Path), 25),
-					TruncateText(pkg.LatestFile, 18),
-					m3u8Count,
-					fmt.Sprintf("%d", pkg.SegmentCount),
-					monitor.FormatFileSize(pkg.TotalSize),
-				})
-			} else {
-				hlsRows = append(hlsRows, table.Row{
-					ch.ID,
-					TruncateText(filepath.Base(ch.Path), 25),
-					"N/A",
-					"No files",
-					"0",
-					"0 B",
-				})
+	healthCounts := make(map[string]int)
+	for _, ch := range channels {
+		if pkg := snapshot.Package(ch.ID); pkg != nil {
+			healthCounts[pkg.Health]++
+			age := "-"
+			if !pkg.PlaylistModTime.IsZero() {
+				age = monitor.FormatDuration(pkg.PlaylistAge())
 			}
-		}
 
-		m.hlsTable.SetRows(hlsRows)
-		m.lastUpdate = time.Now()
-		
-		return nil
+			hlsRows = append(hlsRows, table.Row{
+				ch.ID,
+				TruncateText(filepath.Base(pkg.Path), 25),
+				pkg.Health,
+				age,
+				TruncateText(pkg.LatestFile, 18),
+				fmt.Sprintf("%d", pkg.SegmentCount),
+				monitor.FormatFileSize(pkg.TotalSize),
+			})
+		} else {
+			healthCounts[monitor.HealthMissing]++
+			hlsRows = append(hlsRows, table.Row{
+				ch.ID,
+				TruncateText(filepath.Base(ch.Path), 25),
+				monitor.HealthMissing,
+				"-",
+				"N/A",
+				"0",
+				"0 B",
+			})
+		}
 	}
+
+	m.hlsTable.SetRows(hlsRows)
+	m.healthCounts = healthCounts
+	m.lastUpdate = snapshot.Updated()
 }
 
 func (m *MainViewModel) updateTableSizes() {
 	if m.width > 0 {
 		tableHeight := m.height - 8 // Reserve space for title and status
-		if tableHeight > config.GlobalConfig.Channels.Count+2 {
-			tableHeight = config.GlobalConfig.Channels.Count + 2 // Max channels + header
+		if tableHeight > m.channelCount+2 {
+			tableHeight = m.channelCount + 2 // Max channels + header
 		}
 		if tableHeight < 10 {
 			tableHeight = 10 // Minimum height
 		}
 
-		// Create new tables with updated sizing
-		m.recreateTablesWithSize(tableHeight)
+		m.resizeTables(tableHeight)
 	}
 }
 
-func (m *MainViewModel) recreateTablesWithSize(tableHeight int) {
-	// Recreate FFmpeg table with new height
+// resizeTables fits the columns and height of both tables to the window,
+// keeping their rows, cursors and focus.
+func (m *MainViewModel) resizeTables(tableHeight int) {
 	leftWidth := (m.width - 6) / 2
 	rightWidth := m.width - leftWidth - 6
-	
-	ffmpegColumns := []table.Column{
-		{Title: "Ch", Width: 5},
-		{Title: "Port", Width: 8},
-		{Title: "PID", Width: 8},
-		{Title: "Status", Width: 8},
-		{Title: "Command", Width: max(leftWidth-35, 20)},
-	}
 
-	oldFocused := m.ffmpegTable.Focused()
-	oldCursor := m.ffmpegTable.Cursor()
-	
-	m.ffmpegTable = table.New(
-		table.WithColumns(ffmpegColumns),
-		table.WithHeight(tableHeight),
-	)
-	
-	if oldFocused {
-		m.ffmpegTable.Focus()
-	}
-	m.ffmpegTable.SetCursor(oldCursor)
+	ffmpegColumns := m.ffmpegTable.Columns()
+	ffmpegColumns[len(ffmpegColumns)-1].Width = max(leftWidth-95, 12) // Command
+	m.ffmpegTable.SetColumns(ffmpegColumns)
+	m.ffmpegTable.SetHeight(tableHeight)
 
-	// Recreate HLS table with new height
-	hlsColumns := []table.Column{
-		{Title: "Ch", Width: 5},
-		{Title: "Path", Width: max(rightWidth-60, 15)},
-		{Title: "Latest File", Width: 18},
-		{Title: "M3U8", Width: 12},
-		{Title: "Segs", Width: 6},
-		{Title: "Size", Width: 10},
-	}
+	hlsColumns := m.hlsTable.Columns()
+	hlsColumns[1].Width = max(rightWidth-64, 15) // Path
+	m.hlsTable.SetColumns(hlsColumns)
+	m.hlsTable.SetHeight(tableHeight)
+}
 
-	oldHLSCursor := m.hlsTable.Cursor()
-	
-	m.hlsTable = table.New(
-		table.WithColumns(hlsColumns),
-		table.WithHeight(tableHeight),
-	)
-	
-	if !oldFocused {
-		m.hlsTable.Focus()
+func formatFDCount(count int) string {
+	if count < 0 {
+		return "-"
 	}
-	m.hlsTable.SetCursor(oldHLSCursor)
+	return fmt.Sprintf("%d", count)
+}
 
-	// Apply styles
-	s := table.DefaultStyles()
-	s.Header = s.Header.
-		BorderStyle(lipgloss.NormalBorder()).
-		BorderForeground(lipgloss.Color("240")).
-		BorderBottom(true).
-		Bold(false)
-	s.Selected = s.Selected.
-		Foreground(lipgloss.Color("229")).
-		Background(lipgloss.Color("57")).
-		Bold(false)
+// stoppedStatus is the status of a channel without processes. Discovered
+// channels that are no longer found are shown as gone.
+func stoppedStatus(ch config.Channel) string {
+	if ch.Vanished {
+		return monitor.StatusGone
+	}
+	return monitor.StatusStopped
+}
 
-	m.ffmpegTable.SetStyles(s)
-	m.hlsTable.SetStyles(s)
+// pausedMarker flags a frozen display in the status bar.
+func pausedMarker(paused bool) string {
+	if !paused {
+		return ""
+	}
+	return " " + lipgloss.NewStyle().Foreground(warningColor).Bold(true).Render("PAUSED")
 }
 
-func (m *MainViewModel) updateTableWidths(leftWidth, rightWidth int) {
-	// Tables are recreated with proper sizing during window resize
-	m.recreateTablesWithSize(m.height - 8)
+// formatUpdated renders a snapshot time, or "-" before the first sample.
+func formatUpdated(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("15:04:05")
 }
 
 func max(a, b int) int {
@@ -364,12 +401,20 @@ func max(a, b int) int {
 	return b
 }
 
-func tickCmd() tea.Cmd {
-	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+func tickCmd(cfg *config.Config) tea.Cmd {
+	return tea.Tick(cfg.RefreshIntervals().UI, func(t time.Time) tea.Msg {
 		return tickMsg(t)
 	})
 }
 
 type SwitchToDetailMsg struct {
 	ChannelID string
+}
+
+// ConfigReloadedMsg reports a config reload. Config is the new
+// configuration, or Err is set if it was rejected and the previous one is
+// still active.
+type ConfigReloadedMsg struct {
+	Config *config.Config
+	Err    error
 }
\ No newline at end of file
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"monitorMultiview/internal/config"
+	"monitorMultiview/internal/monitor"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newTestMainView(t *testing.T) *MainViewModel {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.HLS.BasePath = t.TempDir()
+	cfg.Channels.Count = 3
+	collector := monitor.NewCollector(cfg,
+		monitor.NewFFmpegMonitorWithProcRoot(cfg, t.TempDir()),
+		monitor.NewHLSMonitor(cfg))
+	collector.CollectOnce()
+
+	m := NewMainViewModel(cfg, collector)
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 200, Height: 40})
+	return m
+}
+
+func TestMainViewKeepsRowsAcrossRenders(t *testing.T) {
+	m := newTestMainView(t)
+	for i := 0; i < 2; i++ {
+		view := m.View()
+		if got := len(m.ffmpegTable.Rows()); got != 3 {
+			t.Fatalf("render %d: ffmpeg table has %d rows, want 3", i, got)
+		}
+		if got := len(m.hlsTable.Rows()); got != 3 {
+			t.Fatalf("render %d: hls table has %d rows, want 3", i, got)
+		}
+		if !strings.Contains(view, "ch02") {
+			t.Fatalf("render %d: view does not list ch02", i)
+		}
+	}
+}
+
+func TestMainViewRowsFillEveryColumn(t *testing.T) {
+	m := newTestMainView(t)
+	for _, tbl := range []struct {
+		name    string
+		columns int
+		rows    []table.Row
+	}{
+		{"ffmpeg", len(m.ffmpegTable.Columns()), m.ffmpegTable.Rows()},
+		{"hls", len(m.hlsTable.Columns()), m.hlsTable.Rows()},
+	} {
+		for _, row := range tbl.rows {
+			if len(row) != tbl.columns {
+				t.Errorf("%s row %v has %d cells, want %d", tbl.name, row, len(row), tbl.columns)
+			}
+		}
+	}
+}
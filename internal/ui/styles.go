@@ -69,6 +69,13 @@ var (
 	HelpStyle = lipgloss.NewStyle().
 		Foreground(mutedColor).
 		Padding(1, 2)
+	
+	// Banner shown above every view, e.g. for a rejected config reload
+	BannerStyle = lipgloss.NewStyle().
+		Background(errorColor).
+		Foreground(lipgloss.Color("#000000")).
+		Bold(true).
+		Padding(0, 1)
 )
 
 // Status colors
@@ -76,13 +83,37 @@ func GetStatusColor(status string) lipgloss.Style {
 	switch status {
 	case "RUN", "Running":
 		return StatusRunningStyle
-	case "STOP", "Stopped":
+	case "STOP", "Stopped", "ZOMB", "FLAP", "GONE":
 		return StatusStoppedStyle
 	default:
 		return lipgloss.NewStyle().Foreground(warningColor)
 	}
 }
 
+// Health colors
+func GetHealthColor(health string) lipgloss.Style {
+	switch health {
+	case "OK":
+		return StatusRunningStyle
+	case "WARN":
+		return lipgloss.NewStyle().Foreground(warningColor).Bold(true)
+	default:
+		return StatusStoppedStyle
+	}
+}
+
+// Finding severity colors
+func GetSeverityColor(severity string) lipgloss.Style {
+	switch severity {
+	case "ERROR":
+		return lipgloss.NewStyle().Foreground(errorColor)
+	case "WARN":
+		return lipgloss.NewStyle().Foreground(warningColor)
+	default:
+		return lipgloss.NewStyle().Foreground(mutedColor)
+	}
+}
+
 // Truncate text to fit width
 func TruncateText(text string, width int) string {
 	if len(text) <= width {